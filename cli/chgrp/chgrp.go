@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+	"github.com/akutz/gnixutils/lib/os/group"
+	"github.com/akutz/gnixutils/lib/os/walk"
+)
+
+var (
+	recursive bool
+	noDeref   bool
+	verbose   bool
+	changes   bool
+	reference string
+	follow    walk.Follow
+
+	// gid is the new group.
+	gid int
+)
+
+func init() {
+	flag.BoolVar(&recursive, "R", false,
+		"Change files and directories recursively")
+	flag.BoolVar(&noDeref, "h", false,
+		"Change symbolic links instead of the files they refer to")
+	flag.BoolVar(&verbose, "v", false,
+		"Output a diagnostic for every file processed")
+	flag.BoolVar(&changes, "c", false,
+		"Like -v, but report only when a change is made")
+	flag.StringVar(&reference, "reference", "",
+		"Use the group of this file instead of a GROUP value")
+	walk.Flags(flag.CommandLine, &follow)
+}
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+
+	if reference != "" {
+		fi, err := os.Stat(reference)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		var ok bool
+		if _, gid, ok = attr.Owner(fi); !ok {
+			fmt.Printf("chgrp: %s: ownership is not supported\n", reference)
+			os.Exit(1)
+		}
+	} else {
+		if len(args) == 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		var err error
+		if gid, err = lookupGroup(args[0]); err != nil {
+			fmt.Printf("chgrp: invalid group: %q\n", args[0])
+			os.Exit(1)
+		}
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	hasErrs := false
+	for _, p := range args {
+		if !chgrpArg(p) {
+			hasErrs = true
+		}
+	}
+
+	if hasErrs {
+		os.Exit(1)
+	}
+}
+
+// lookupGroup returns the ID of the group with the given name or numeric
+// ID. A leading + marks a number that is not to be looked up as a name.
+func lookupGroup(name string) (int, error) {
+	if grp, err := group.LookupGroup(name); err == nil {
+		return strconv.Atoi(grp.ID)
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(name, "+"))
+	if err != nil || id < 0 {
+		return -1, fmt.Errorf("invalid group %q", name)
+	}
+	return id, nil
+}
+
+// chgrpArg changes the group of the file p given on the command line, and
+// of its contents with -R. It returns false if any change failed.
+func chgrpArg(p string) bool {
+	if !recursive {
+		fi, err := stat(p, !noDeref)
+		if err != nil {
+			fmt.Println(err.Error())
+			return false
+		}
+		return chgrp(p, fi)
+	}
+
+	ok := true
+	f := walk.Resolve(follow, noDeref)
+	walk.Walk(p, f, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			fmt.Println(err.Error())
+			ok = false
+			return nil
+		}
+		if !chgrp(p, fi) {
+			ok = false
+		}
+		return nil
+	})
+	return ok
+}
+
+// chgrp changes the group of the file p, described by fi. If fi describes
+// a symbolic link then the link itself is changed.
+func chgrp(p string, fi os.FileInfo) bool {
+	_, oldGID, _ := attr.Owner(fi)
+	old, now := groupName(oldGID), groupName(gid)
+
+	var err error
+	if fi.Mode()&os.ModeSymlink != 0 {
+		err = os.Lchown(p, -1, gid)
+	} else {
+		err = os.Chown(p, -1, gid)
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		if verbose {
+			fmt.Printf("failed to change group of '%s' from %s to %s\n",
+				p, old, now)
+		}
+		return false
+	}
+
+	switch {
+	case oldGID != gid && (verbose || changes):
+		fmt.Printf("changed group of '%s' from %s to %s\n", p, old, now)
+	case oldGID == gid && verbose:
+		fmt.Printf("group of '%s' retained as %s\n", p, old)
+	}
+	return true
+}
+
+// groupName returns the name of the group g, or its ID if it has no name.
+func groupName(g int) string {
+	s := strconv.Itoa(g)
+	if grp, err := group.LookupGroupID(s); err == nil && grp.Name != "" {
+		return grp.Name
+	}
+	return s
+}
+
+func stat(p string, deref bool) (os.FileInfo, error) {
+	if deref {
+		return os.Stat(p)
+	}
+	return os.Lstat(p)
+}
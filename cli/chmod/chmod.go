@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/mode"
+	"github.com/akutz/gnixutils/lib/os/walk"
+)
+
+var (
+	recursive bool
+	noDeref   bool
+	verbose   bool
+	changes   bool
+	reference string
+	follow    walk.Follow
+
+	// refMode is the mode of the --reference file.
+	refMode os.FileMode
+	newMode *mode.Mode
+	umask   os.FileMode
+)
+
+// modeBits are the bits of an os.FileMode that chmod changes.
+const modeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+func init() {
+	flag.BoolVar(&recursive, "R", false,
+		"Change files and directories recursively")
+	flag.BoolVar(&noDeref, "h", false,
+		"Do not follow symbolic links; leave them unchanged")
+	flag.BoolVar(&verbose, "v", false,
+		"Output a diagnostic for every file processed")
+	flag.BoolVar(&changes, "c", false,
+		"Like -v, but report only when a change is made")
+	flag.StringVar(&reference, "reference", "",
+		"Use the mode of this file instead of a MODE value")
+	walk.Flags(flag.CommandLine, &follow)
+}
+
+func main() {
+	args, modeArg := splitMode(os.Args[1:])
+	flag.CommandLine.Parse(args)
+	args = flag.Args()
+
+	if reference != "" {
+		fi, err := os.Stat(reference)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		refMode = fi.Mode() & modeBits
+	} else {
+		if modeArg == "" && len(args) > 0 {
+			modeArg, args = args[0], args[1:]
+		}
+		if modeArg == "" {
+			flag.Usage()
+			os.Exit(1)
+		}
+		m, err := mode.Parse(modeArg)
+		if err != nil {
+			fmt.Printf("chmod: %s\n", err)
+			os.Exit(1)
+		}
+		newMode = m
+		umask = mode.Umask()
+	}
+
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	hasErrs := false
+	for _, p := range args {
+		if !chmodArg(p) {
+			hasErrs = true
+		}
+	}
+
+	if hasErrs {
+		os.Exit(1)
+	}
+}
+
+// splitMode removes a mode that begins with a dash, such as -w, from the
+// command line arguments so that it is not taken for a flag.
+func splitMode(args []string) ([]string, string) {
+	for i, a := range args {
+		if a == "--" || !strings.HasPrefix(a, "-") {
+			break
+		}
+		name := strings.TrimLeft(a, "-")
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i]
+		}
+		if flag.Lookup(name) != nil {
+			continue
+		}
+		if _, err := mode.Parse(a); err == nil {
+			rest := append([]string{}, args[:i]...)
+			return append(rest, args[i+1:]...), a
+		}
+	}
+	return args, ""
+}
+
+// chmodArg changes the mode of the file p given on the command line, and
+// of its contents with -R. It returns false if any change failed.
+func chmodArg(p string) bool {
+	if !recursive {
+		fi, err := stat(p, !noDeref)
+		if err != nil {
+			fmt.Println(err.Error())
+			return false
+		}
+		return chmod(p, fi)
+	}
+
+	ok := true
+	f := walk.Resolve(follow, noDeref)
+	walk.Walk(p, f, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			fmt.Println(err.Error())
+			ok = false
+			return nil
+		}
+		if !chmod(p, fi) {
+			ok = false
+		}
+		return nil
+	})
+	return ok
+}
+
+// chmod changes the mode of the file p, described by fi.
+func chmod(p string, fi os.FileInfo) bool {
+	// the mode of a symbolic link cannot be changed on most systems
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if verbose {
+			fmt.Printf(
+				"neither symbolic link '%s' nor referent has been changed\n",
+				p)
+		}
+		return true
+	}
+
+	old := fi.Mode()
+	var m os.FileMode
+	if newMode != nil {
+		m = newMode.Apply(old, umask)
+	} else {
+		m = old&^modeBits | refMode
+	}
+
+	if m != old {
+		if err := os.Chmod(p, m); err != nil {
+			fmt.Println(err.Error())
+			if verbose {
+				fmt.Printf("failed to change mode of '%s' from %s to %s\n",
+					p, describe(old), describe(m))
+			}
+			return false
+		}
+	}
+
+	switch {
+	case m != old && (verbose || changes):
+		fmt.Printf("mode of '%s' changed from %s to %s\n",
+			p, describe(old), describe(m))
+	case m == old && verbose:
+		fmt.Printf("mode of '%s' retained as %s\n", p, describe(old))
+	}
+	return true
+}
+
+// describe formats m as chmod -v does, such as 0755 (rwxr-xr-x).
+func describe(m os.FileMode) string {
+	return fmt.Sprintf("%04o (%s)", mode.ToUnix(m), mode.String(m))
+}
+
+func stat(p string, deref bool) (os.FileInfo, error) {
+	if deref {
+		return os.Stat(p)
+	}
+	return os.Lstat(p)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+	"github.com/akutz/gnixutils/lib/os/group"
+	"github.com/akutz/gnixutils/lib/os/user"
+	"github.com/akutz/gnixutils/lib/os/walk"
+)
+
+var (
+	recursive bool
+	noDeref   bool
+	verbose   bool
+	changes   bool
+	reference string
+	follow    walk.Follow
+
+	// uid and gid are the new owner and group, or -1 if they are not
+	// changed.
+	uid = -1
+	gid = -1
+)
+
+func init() {
+	flag.BoolVar(&recursive, "R", false,
+		"Change files and directories recursively")
+	flag.BoolVar(&noDeref, "h", false,
+		"Change symbolic links instead of the files they refer to")
+	flag.BoolVar(&verbose, "v", false,
+		"Output a diagnostic for every file processed")
+	flag.BoolVar(&changes, "c", false,
+		"Like -v, but report only when a change is made")
+	flag.StringVar(&reference, "reference", "",
+		"Use the owner and group of this file instead of an OWNER value")
+	walk.Flags(flag.CommandLine, &follow)
+}
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+
+	if reference != "" {
+		fi, err := os.Stat(reference)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		var ok bool
+		if uid, gid, ok = attr.Owner(fi); !ok {
+			fmt.Printf("chown: %s: ownership is not supported\n", reference)
+			os.Exit(1)
+		}
+	} else {
+		if len(args) == 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		var err error
+		if uid, gid, err = parseOwner(args[0]); err != nil {
+			fmt.Printf("chown: %s\n", err)
+			os.Exit(1)
+		}
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	hasErrs := false
+	for _, p := range args {
+		if !chownArg(p) {
+			hasErrs = true
+		}
+	}
+
+	if hasErrs {
+		os.Exit(1)
+	}
+}
+
+// parseOwner parses OWNER[:[GROUP]] or :GROUP. If OWNER is followed by a
+// colon but no group then the group is the owner's login group. Names are
+// looked up first, and a name that is not found may be a numeric ID.
+func parseOwner(spec string) (int, int, error) {
+	owner, grp := spec, ""
+	hasGroup := false
+	if i := strings.Index(spec, ":"); i >= 0 {
+		owner, grp, hasGroup = spec[:i], spec[i+1:], true
+	}
+
+	u, g := -1, -1
+	if owner != "" {
+		usr, err := user.LookupUser(owner)
+		if err == nil {
+			u, _ = strconv.Atoi(usr.ID)
+			if hasGroup && grp == "" {
+				g, _ = strconv.Atoi(usr.GroupID)
+			}
+		} else if u, err = parseID(owner); err != nil {
+			return -1, -1, fmt.Errorf("invalid user: %q", spec)
+		} else if hasGroup && grp == "" {
+			return -1, -1, fmt.Errorf("invalid spec: %q", spec)
+		}
+	}
+
+	if grp != "" {
+		var err error
+		if g, err = lookupGroup(grp); err != nil {
+			return -1, -1, fmt.Errorf("invalid group: %q", spec)
+		}
+	}
+
+	return u, g, nil
+}
+
+// lookupGroup returns the ID of the group with the given name or numeric
+// ID.
+func lookupGroup(name string) (int, error) {
+	if grp, err := group.LookupGroup(name); err == nil {
+		return strconv.Atoi(grp.ID)
+	}
+	return parseID(name)
+}
+
+// parseID parses a numeric user or group ID. A leading + is ignored; it
+// marks a number that is not to be looked up as a name.
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
+	if err != nil || id < 0 {
+		return -1, fmt.Errorf("invalid id %q", s)
+	}
+	return id, nil
+}
+
+// chownArg changes the ownership of the file p given on the command line,
+// and of its contents with -R. It returns false if any change failed.
+func chownArg(p string) bool {
+	if !recursive {
+		fi, err := stat(p, !noDeref)
+		if err != nil {
+			fmt.Println(err.Error())
+			return false
+		}
+		return chown(p, fi)
+	}
+
+	ok := true
+	f := walk.Resolve(follow, noDeref)
+	walk.Walk(p, f, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			fmt.Println(err.Error())
+			ok = false
+			return nil
+		}
+		if !chown(p, fi) {
+			ok = false
+		}
+		return nil
+	})
+	return ok
+}
+
+// chown changes the ownership of the file p, described by fi. If fi
+// describes a symbolic link then the link itself is changed.
+func chown(p string, fi os.FileInfo) bool {
+	oldUID, oldGID, _ := attr.Owner(fi)
+	newUID, newGID := uid, gid
+	if newUID == -1 {
+		newUID = oldUID
+	}
+	if newGID == -1 {
+		newGID = oldGID
+	}
+
+	old := ownership(oldUID, oldGID)
+	now := ownership(newUID, newGID)
+
+	var err error
+	if fi.Mode()&os.ModeSymlink != 0 {
+		err = os.Lchown(p, uid, gid)
+	} else {
+		err = os.Chown(p, uid, gid)
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		if verbose {
+			fmt.Printf("failed to change ownership of '%s' from %s to %s\n",
+				p, old, now)
+		}
+		return false
+	}
+
+	switch {
+	case old != now && (verbose || changes):
+		fmt.Printf("changed ownership of '%s' from %s to %s\n", p, old, now)
+	case old == now && verbose:
+		fmt.Printf("ownership of '%s' retained as %s\n", p, old)
+	}
+	return true
+}
+
+// ownership formats the user and group IDs as names, or as numbers if
+// they have no names.
+func ownership(u, g int) string {
+	us := strconv.Itoa(u)
+	if usr, err := user.LookupUserID(us); err == nil && usr.Username != "" {
+		us = usr.Username
+	}
+	gs := strconv.Itoa(g)
+	if grp, err := group.LookupGroupID(gs); err == nil && grp.Name != "" {
+		gs = grp.Name
+	}
+	return us + ":" + gs
+}
+
+func stat(p string, deref bool) (os.FileInfo, error) {
+	if deref {
+		return os.Stat(p)
+	}
+	return os.Lstat(p)
+}
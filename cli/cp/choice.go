@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// choice is a flag.Value that accepts one of a fixed set of values. If
+// implied is not empty then the flag may also be given without a value, as
+// in --reflink, in which case the flag is set to implied.
+type choice struct {
+	value   string
+	choices []string
+	implied string
+}
+
+func (c *choice) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.value
+}
+
+func (c *choice) Set(s string) error {
+	if s == "true" && c.implied != "" {
+		c.value = c.implied
+		return nil
+	}
+	for _, v := range c.choices {
+		if s == v {
+			c.value = s
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"invalid argument %q; valid arguments are %s",
+		s, strings.Join(c.choices, ", "))
+}
+
+func (c *choice) IsBoolFlag() bool {
+	return c.implied != ""
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+const (
+	// sparseBlockSize is the size of the blocks that are checked for zeros
+	// when creating holes in a copy.
+	sparseBlockSize = 4096
+
+	// sparseBufSize is the size of the buffer used for sparse copies.
+	sparseBufSize = 32 * sparseBlockSize
+
+	// copyChunkSize is the most data copied in the kernel at once, so that
+	// the progress of large copies can be measured. See chunkSize.
+	copyChunkSize = 4 << 20
+)
+
+var (
+	errReflinkUnsupported = errors.New(
+		"cp: reflink copies are not supported on this platform")
+
+	reflink = &choice{
+		value:   "auto",
+		choices: []string{"auto", "always", "never"},
+		implied: "always",
+	}
+
+	sparse = &choice{
+		value:   "auto",
+		choices: []string{"auto", "always", "never"},
+	}
+)
+
+// copySparse copies size bytes from the current offset of src to the
+// current offset of dst. Blocks of zeros are skipped over rather than
+// written so that they become holes in dst.
+func copySparse(dst, src *os.File, size int64) error {
+	buf := make([]byte, sparseBufSize)
+	var hole int64
+
+	for size > 0 {
+		n := int64(len(buf))
+		if c := chunkSize(); c < n {
+			n = c
+		}
+		if size < n {
+			n = size
+		}
+		nr, err := io.ReadFull(src, buf[:n])
+		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			if nr == 0 {
+				if err == io.EOF {
+					break
+				}
+				return err
+			}
+		}
+
+		for off := 0; off < nr; off += sparseBlockSize {
+			end := off + sparseBlockSize
+			if end > nr {
+				end = nr
+			}
+			block := buf[off:end]
+			if isZero(block) {
+				hole += int64(len(block))
+				continue
+			}
+			if hole > 0 {
+				if _, err := dst.Seek(hole, io.SeekCurrent); err != nil {
+					return err
+				}
+				hole = 0
+			}
+			if _, err := dst.Write(block); err != nil {
+				return err
+			}
+		}
+
+		transferred(int64(nr))
+		size -= int64(nr)
+	}
+
+	// a trailing hole is created by extending the file
+	if hole > 0 {
+		off, err := dst.Seek(hole, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		return dst.Truncate(off)
+	}
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyData copies the contents of src, described by fi, to the empty file
+// dst. The data is shared with a reflink if the file system supports it
+// and is otherwise copied in the kernel with copy_file_range. Holes in
+// sparse files are recreated by copying only the file's data segments. A
+// reflink shares all of the data at once, so with --bwlimit it is only
+// used if --reflink=always is given.
+func copyData(dst, src *os.File, fi os.FileInfo) error {
+	if reflink.value == "always" || (reflink.value == "auto" && bwlimit <= 0) {
+		err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+		if err == nil {
+			atomic.AddInt64(&copiedBytes, fi.Size())
+			return nil
+		}
+		if reflink.value == "always" {
+			return &os.PathError{Op: "clone", Path: dst.Name(), Err: err}
+		}
+	}
+
+	switch sparse.value {
+	case "always":
+		return copySparse(dst, src, fi.Size())
+	case "auto":
+		if isSparse(fi) {
+			return copyDataSegments(dst, src, fi.Size())
+		}
+	}
+
+	return copyRange(dst, src, 0, fi.Size())
+}
+
+// copyDataSegments copies the data segments of the first size bytes of src
+// to the same offsets in dst, leaving holes where src has holes.
+func copyDataSegments(dst, src *os.File, size int64) error {
+	fd := int(src.Fd())
+
+	for off := int64(0); off < size; {
+		data, err := unix.Seek(fd, off, unix.SEEK_DATA)
+		if err == unix.ENXIO {
+			// the rest of the file is a hole
+			atomic.AddInt64(&copiedBytes, size-off)
+			break
+		}
+		if err != nil {
+			if off == 0 {
+				// the file system cannot report holes
+				return copySparse(dst, src, size)
+			}
+			return &os.PathError{Op: "lseek", Path: src.Name(), Err: err}
+		}
+		hole, err := unix.Seek(fd, data, unix.SEEK_HOLE)
+		if err != nil {
+			return &os.PathError{Op: "lseek", Path: src.Name(), Err: err}
+		}
+		if hole > size {
+			hole = size
+		}
+		atomic.AddInt64(&copiedBytes, data-off)
+		if err := copyRange(dst, src, data, hole-data); err != nil {
+			return err
+		}
+		off = hole
+	}
+
+	return dst.Truncate(size)
+}
+
+// copyRange copies n bytes at offset off in src to the same offset in dst.
+// The bytes are copied with copy_file_range, falling back to copying them
+// through userspace if the kernel or file systems do not support it.
+func copyRange(dst, src *os.File, off, n int64) error {
+	roff, woff := off, off
+
+	for n > 0 {
+		chunk := n
+		if c := chunkSize(); chunk > c {
+			chunk = c
+		}
+		c, err := unix.CopyFileRange(
+			int(src.Fd()), &roff, int(dst.Fd()), &woff, int(chunk), 0)
+		if err != nil {
+			if !copyRangeUnsupported(err) {
+				return &os.PathError{
+					Op: "copy_file_range", Path: dst.Name(), Err: err}
+			}
+			if _, err := dst.Seek(woff, io.SeekStart); err != nil {
+				return err
+			}
+			_, err = io.Copy(
+				&meter{w: dst}, io.NewSectionReader(src, roff, n))
+			return err
+		}
+		if c == 0 {
+			// the source file was truncated while being copied
+			break
+		}
+		transferred(int64(c))
+		n -= int64(c)
+	}
+
+	return nil
+}
+
+func copyRangeUnsupported(err error) bool {
+	switch err {
+	case unix.ENOSYS, unix.EXDEV, unix.EINVAL, unix.EOPNOTSUPP,
+		unix.EPERM, unix.EBADF, unix.ETXTBSY:
+		return true
+	}
+	return false
+}
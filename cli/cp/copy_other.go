@@ -0,0 +1,23 @@
+// +build !linux
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// copyData copies the contents of src, described by fi, to the empty file
+// dst. Reflinks are only supported on Linux.
+func copyData(dst, src *os.File, fi os.FileInfo) error {
+	if reflink.value == "always" {
+		return errReflinkUnsupported
+	}
+
+	if sparse.value == "always" || (sparse.value == "auto" && isSparse(fi)) {
+		return copySparse(dst, src, fi.Size())
+	}
+
+	_, err := io.Copy(&meter{w: dst}, src)
+	return err
+}
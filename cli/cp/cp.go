@@ -1,77 +1,245 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path"
-	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+	"github.com/akutz/gnixutils/lib/os/backup"
+	"github.com/akutz/gnixutils/lib/os/mode"
+	"github.com/akutz/gnixutils/lib/os/partial"
 )
 
 var (
-	errSourceIsDir = errors.New("source file is directory")
-	errNoForce     = errors.New("destination path exists; force is not enabled")
+	force           bool
+	interactive     bool
+	noClobber       bool
+	update          bool
+	simpleBackup    bool
+	backupControl   backup.Control
+	backupSuffix    string
+	recursive       bool
+	verbose         bool
+	archive         bool
+	deref           derefMode
+	preserveDefault bool
+	preserve        = &attrs{}
+	jobs            int
+
+	// umask is read once, before any files are created, since reading it
+	// means briefly changing it
+	umask os.FileMode
+
+	// workers limits the number of goroutines copying directory entries in
+	// addition to the main copy goroutine
+	workers chan struct{}
+)
 
-	rmTrailingSlashRx *regexp.Regexp
+// reporter receives the errors and verbose output of a copy.
+type reporter func(interface{})
 
-	force     bool
-	recursive bool
-	verbose   bool
-)
+// notice is verbose output. Unlike the other messages sent to a reporter it
+// does not indicate a failure.
+type notice string
 
-func init() {
-	rmTrailingSlashRx = regexp.MustCompile(`^(.*?)[/\\]?$`)
+// skipped is an error for a file that was skipped rather than failed to
+// copy, such as a file that is its own destination.
+type skipped string
 
+func init() {
 	flag.BoolVar(&force, "f", false,
-		"If the destination exists, remove it and create a new file")
+		"If an existing destination file cannot be replaced, remove it "+
+			"and try again")
+	flag.BoolVar(&interactive, "i", false,
+		"Prompt before overwriting an existing file")
+	flag.BoolVar(&noClobber, "n", false,
+		"Do not overwrite an existing file. Overrides -i and -f.")
+	flag.BoolVar(&update, "u", false,
+		"Copy only when the source file is newer than the destination "+
+			"file or when the destination file is missing")
+	flag.Var(&backupControl, "backup",
+		"Make a backup of each existing destination file: none, numbered, "+
+			"existing or simple. If given without a value then existing.")
+	flag.BoolVar(&simpleBackup, "b", false,
+		"Like --backup but does not accept a value")
+	flag.StringVar(&backupSuffix, "S", backup.DefaultSuffix,
+		"Override the usual backup suffix")
+	flag.BoolVar(&resume, "resume", false,
+		"Resume an interrupted copy of a file, keeping the data already "+
+			"copied if it matches the source")
+	flag.Var(verify, "verify",
+		"Verify each copied file by comparing its checksum with the "+
+			"source's: none or sha256")
+	flag.BoolVar(&showProgress, "progress", false,
+		"Show the overall progress of the copy on stderr")
+	flag.BoolVar(&showStats, "stats", false,
+		"Print a summary of the copy once it is complete")
+	flag.Var(&bwlimit, "bwlimit",
+		"Limit the copy to RATE bytes per second. RATE is in KiB unless it "+
+			"has a suffix of K, M or G. Files are not reflinked.")
+	flag.Var(filterFlag(false), "exclude",
+		"Do not copy files matching PATTERN. May be given more than once.")
+	flag.Var(filterFlag(true), "include",
+		"Copy files matching PATTERN even if a later --exclude matches "+
+			"them. May be given more than once.")
+	flag.Var(excludeFromFlag{}, "exclude-from",
+		"Read exclude patterns from FILE, one per line")
+	flag.BoolVar(&syncMode, "sync", false,
+		"Synchronize the destination with the source. Implies -r, "+
+			"--preserve=timestamps and, as with rsync, "+
+			"--trailing-slash=bsd. Files whose size and modification time "+
+			"match the source are not copied.")
+	flag.BoolVar(&checksum, "checksum", false,
+		"In sync mode, compare files by checksum rather than size and "+
+			"modification time")
+	flag.BoolVar(&deleteExtraneous, "delete", false,
+		"In sync mode, delete destination files that are not in the source")
+	flag.StringVar(&targetDir, "t", "",
+		"Copy all source arguments into the directory DIR")
+	flag.BoolVar(&noTargetDir, "T", false,
+		"Treat the destination as a normal file")
+	flag.BoolVar(&parents, "parents", false,
+		"Append each source path to the destination directory, creating "+
+			"its parent directories as required")
+	flag.Var(trailingSlash, "trailing-slash",
+		"How a source directory with a trailing slash is copied into an "+
+			"existing directory: gnu copies the directory, bsd copies its "+
+			"contents")
 	flag.BoolVar(&recursive, "r", false,
 		"Recursively copy directories")
 	flag.BoolVar(&recursive, "R", false,
 		"Recursively copy directories")
 	flag.BoolVar(&verbose, "v", false,
 		"Cause cp to be verbose, showing files as they are copied.")
+	flag.BoolVar(&archive, "a", false,
+		"Archive mode. Same as -R -P --preserve=all")
+	flag.Var(derefFlag(derefNever), "P",
+		"Copy symbolic links as symbolic links. This is the default when "+
+			"copying recursively.")
+	flag.Var(derefFlag(derefAlways), "L",
+		"Follow all symbolic links in the source")
+	flag.Var(derefFlag(derefArgs), "H",
+		"Follow symbolic links given on the command line only")
+	flag.BoolVar(&preserveDefault, "p", false,
+		"Same as --preserve=mode,ownership,timestamps")
+	flag.Var(preserve, "preserve",
+		"Preserve the specified attributes (default: "+
+			"mode,ownership,timestamps). Additional attributes are "+
+			"xattr, links and all.")
+	flag.IntVar(&jobs, "jobs", 1,
+		"Copy the entries of directories using up to N concurrent jobs")
+	flag.Var(reflink, "reflink",
+		"Control copy-on-write clones: auto, always or never. If given "+
+			"without a value then always.")
+	flag.Var(sparse, "sparse",
+		"Control the creation of sparse files: auto, always or never")
 }
 
 func main() {
 	flag.Parse()
 
+	if archive {
+		recursive = true
+		if deref == derefDefault {
+			deref = derefNever
+		}
+		preserve.setAll()
+	}
+	if deref == derefDefault {
+		if recursive {
+			deref = derefNever
+		} else {
+			deref = derefAlways
+		}
+	}
+	if preserveDefault {
+		preserve.setDefault()
+	}
+	if syncMode {
+		recursive = true
+		preserve.timestamps = true
+		if !isFlagSet("trailing-slash") {
+			trailingSlash.value = "bsd"
+		}
+	}
+	if simpleBackup && backupControl == backup.None {
+		backupControl = backup.Existing
+	}
+
 	fargs := flag.Args()
 
-	// there needs to be at least two arguments, a source and target path
-	if len(fargs) < 2 {
+	var from []string
+	var to string
+
+	if targetDir != "" {
+		from, to = fargs, targetDir
+	} else if len(fargs) > 1 {
+		from, to = fargs[:len(fargs)-1], fargs[len(fargs)-1]
+	}
+
+	// there needs to be at least one source path and a target path, and
+	// with -T exactly one source path
+	if len(from) == 0 || (noTargetDir && (targetDir != "" || len(from) > 1)) {
 		flag.Usage()
 		os.Exit(64)
 	}
 
-	from := fargs[:len(fargs)-1]
-	to := fargs[len(fargs)-1]
-
 	var toIsDir bool
-	toFile, err := os.Stat(to)
-
-	if err == nil {
-		toIsDir = toFile.IsDir()
+	if !noTargetDir {
+		if toFile, err := os.Stat(to); err == nil {
+			toIsDir = toFile.IsDir()
+		}
 	}
 
 	// cannot copy more than one source file to a target path if the target
 	// path is not a directory
-	if !toIsDir && len(from) > 1 {
-		flag.Usage()
+	if !toIsDir && (targetDir != "" || parents || len(from) > 1) {
+		fmt.Printf("cp: %s: Not a directory\n", to)
 		os.Exit(64)
 	}
 
-	// remove a trailing slash from the to path if it has one
-	mTo := rmTrailingSlashRx.FindStringSubmatch(to)
-	if len(mTo) == 2 {
-		to = mTo[1]
+	if jobs > 1 {
+		workers = make(chan struct{}, jobs-1)
+	}
+	umask = mode.Umask()
+
+	if showProgress {
+		for _, p := range from {
+			scan(p, "", nil)
+		}
+	}
+
+	// the start time is set before the progress goroutine reads it
+	startCopy()
+
+	var progressDone chan struct{}
+	progressExited := make(chan struct{})
+	if showProgress {
+		progressDone = make(chan struct{})
+		go func() {
+			progress(500*time.Millisecond, progressDone)
+			close(progressExited)
+		}()
 	}
 
 	errc := make(chan interface{})
 	go func() {
+		report := func(m interface{}) {
+			errc <- m
+		}
 		for _, p := range from {
-			cp(p, to, errc)
+			if parents {
+				if err := mkParents(p, to); err != nil {
+					report(err)
+					continue
+				}
+			}
+			cp(p, destination(p, to, toIsDir), "", nil, nil, report)
 		}
 		close(errc)
 	}()
@@ -79,119 +247,469 @@ func main() {
 	hasErrs := false
 	for e := range errc {
 		switch te := e.(type) {
+		case notice:
+			fmt.Println(string(te))
+		case skipped:
+			fmt.Println(string(te))
+			hasErrs = true
 		case string:
 			fmt.Println(te)
 			hasErrs = true
+			failedFiles++
 		case error:
 			fmt.Println(te.Error())
 			hasErrs = true
+			failedFiles++
 		}
 	}
 
+	if showProgress {
+		close(progressDone)
+		<-progressExited
+	}
+	if showStats {
+		printStats()
+	}
+
 	if hasErrs {
 		os.Exit(1)
 	}
 }
 
-func cp(from, to string, errc chan interface{}) {
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
 
-	fromFileInfo, err := os.Stat(from)
-	if err != nil && os.IsNotExist(err) {
-		errc <- fmt.Sprintf("cp: %s: No such file or directory", from)
+// cp copies from to to. The path rel is the path of from relative to the
+// source given on the command line, the ancestors are the IDs of the
+// source directories that contain from, and the dests are the IDs of the
+// directories that contain to. Both are nil for the sources given on the
+// command line.
+func cp(
+	from, to, rel string,
+	ancestors, dests []attr.FileID,
+	report reporter) {
+
+	fromFileInfo, err := statSource(from, ancestors == nil)
+	if err != nil {
+		if os.IsNotExist(err) {
+			report(fmt.Sprintf("cp: %s: No such file or directory", from))
+		} else {
+			report(err)
+		}
 		return
 	}
 
 	fromFileIsDir := fromFileInfo.IsDir()
 	if !recursive && fromFileIsDir {
-		errc <- fmt.Sprintf("cp: %s is a directory (not copied)", from)
+		report(fmt.Sprintf("cp: %s is a directory (not copied)", from))
 		return
 	}
 
 	toFileInfo, err := os.Stat(to)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			report(err)
+			return
+		}
+		toFileInfo = nil
+	}
 
-	// destination path does not exist
-	if err != nil && os.IsNotExist(err) {
-		if fromFileIsDir {
-
-			if err := os.Mkdir(to, fromFileInfo.Mode()); err != nil {
-				errc <- err
-				return
-			}
-
-			fromDir, err := os.Open(from)
-			if err != nil {
-				errc <- err
-				return
-			}
-
-			fromDirObjs, err := fromDir.Readdir(-1)
-			if err != nil {
-				errc <- err
+	// the destination path exists. directories are merged, while other
+	// files are replaced according to the overwrite policy
+	if toFileInfo != nil {
+		switch {
+		case fromFileIsDir && toFileInfo.IsDir():
+		case fromFileIsDir:
+			report(fmt.Sprintf(
+				"cp: cannot overwrite non-directory %s with directory %s",
+				to, from))
+			return
+		case toFileInfo.IsDir():
+			report(fmt.Sprintf(
+				"cp: cannot overwrite directory %s with non-directory %s",
+				to, from))
+			return
+		case os.SameFile(fromFileInfo, toFileInfo):
+			atomic.AddInt64(&skippedFiles, 1)
+			report(skipped(fmt.Sprintf(
+				"cp: %s and %s are identical (not copied)", from, to)))
+			return
+		case unchanged(from, to, fromFileInfo, toFileInfo):
+			atomic.AddInt64(&skippedFiles, 1)
+			return
+		default:
+			if !overwrite(from, to, fromFileInfo, toFileInfo, report) {
 				return
 			}
+		}
+	}
 
-			for _, o := range fromDirObjs {
+	switch {
+	case fromFileInfo.Mode()&os.ModeSymlink != 0:
+		cpSymlink(from, to, fromFileInfo, report)
+	case fromFileIsDir:
+		cpDir(from, to, rel, fromFileInfo, toFileInfo != nil,
+			ancestors, dests, report)
+	default:
+		linked, copied := cpHardLink(from, to, fromFileInfo, report)
+		if linked {
+			return
+		}
+		cpFileToFile(from, to, fromFileInfo, report)
+		copied()
+	}
+}
 
-				oName := o.Name()
-				oFrom := path.Join(from, oName)
-				oTo := path.Join(to, oName)
+// cpDir copies the directory from to the directory to, which is created
+// unless it already exists. The directory's
+// attributes are applied only after its contents have been copied so that
+// a read-only source directory can still be populated, and so that the
+// copied timestamps are not disturbed by the creation of its children.
+func cpDir(
+	from, to, rel string,
+	fromFileInfo os.FileInfo,
+	exists bool,
+	ancestors, dests []attr.FileID,
+	report reporter) {
+
+	// following symbolic links may lead back to a directory that is already
+	// being copied, or to the destination or one of its ancestors
+	if isCycle(fromFileInfo, ancestors) {
+		report(fmt.Sprintf("cp: %s: directory causes a cycle", from))
+		return
+	}
+	if dests == nil {
+		dests = destAncestors(to)
+	}
+	if isCycle(fromFileInfo, dests) {
+		report(fmt.Sprintf(
+			"cp: cannot copy a directory, %s, into itself, %s", from, to))
+		return
+	}
+	if k, _, ok := attr.ID(fromFileInfo); ok {
+		ancestors = append(ancestors[:len(ancestors):len(ancestors)], k)
+	} else if ancestors == nil {
+		ancestors = []attr.FileID{}
+	}
 
-				cp(oFrom, oTo, errc)
-			}
+	perm := fromFileInfo.Mode().Perm()
 
-			// copy contents of from dir to the to dir
-		} else {
-			cpFileToFile(from, to, errc)
+	// the owner must be able to write to and search the directory while its
+	// contents are being copied
+	if !exists {
+		if err := os.Mkdir(to, perm|0700); err != nil {
+			report(err)
+			return
+		}
+	}
+	if toFileInfo, err := os.Stat(to); err == nil {
+		if k, _, ok := attr.ID(toFileInfo); ok {
+			dests = append(dests[:len(dests):len(dests)], k)
 		}
+	}
 
+	fromDir, err := os.Open(from)
+	if err != nil {
+		report(err)
+		return
+	}
+	defer fromDir.Close()
+
+	fromDirObjs, err := fromDir.Readdir(-1)
+	if err != nil {
+		report(err)
 		return
 	}
 
-	// destination path does exist. if the force flag is enabled nuke the
-	// destination path
-	if force {
-		if err := os.RemoveAll(to); err != nil {
-			errc <- err
+	if exists && syncMode && deleteExtraneous {
+		deleteEntries(to, rel, fromDirObjs, report)
+	}
+
+	fromDirObjs = filterEntries(rel, fromDirObjs)
+
+	if workers == nil {
+		for _, o := range fromDirObjs {
+
+			oName := o.Name()
+			oFrom := path.Join(from, oName)
+			oTo := path.Join(to, oName)
+			oRel := path.Join(rel, oName)
+
+			cp(oFrom, oTo, oRel, ancestors, dests, report)
+		}
+	} else {
+		cpDirEntries(from, to, rel, fromDirObjs, ancestors, dests, report)
+	}
+
+	// if the mode is not preserved then the directory gets the source
+	// directory's permissions less the umask, so only the owner bits that
+	// were added above need to be taken away again
+	if !exists && !preserve.mode && perm&0700 != 0700 {
+		toFileInfo, err := os.Stat(to)
+		if err != nil {
+			report(err)
+			return
+		}
+		if err := os.Chmod(
+			to, toFileInfo.Mode().Perm()&^(0700&^perm)); err != nil {
+			report(err)
 			return
 		}
-		cp(from, to, errc)
+	}
+
+	if err := preserveAttrs(from, to, fromFileInfo); err != nil {
+		report(err)
+	}
+}
+
+// cpDirEntries copies the directory entries objs concurrently. An entry is
+// handed to a worker if one is free and is otherwise copied by the calling
+// goroutine, so nested directories never wait on one another for workers.
+// The output of each entry is buffered and reported in the order of objs
+// once the entry is copied, so the output does not depend on scheduling.
+func cpDirEntries(
+	from, to, rel string,
+	objs []os.FileInfo,
+	ancestors, dests []attr.FileID,
+	report reporter) {
+
+	outs := make([][]interface{}, len(objs))
+	done := make([]chan struct{}, len(objs))
+
+	for i, o := range objs {
+		oName := o.Name()
+		oFrom := path.Join(from, oName)
+		oTo := path.Join(to, oName)
+		oRel := path.Join(rel, oName)
+
+		out := &outs[i]
+		done[i] = make(chan struct{})
+
+		run := func(c chan struct{}) {
+			defer close(c)
+			cp(oFrom, oTo, oRel, ancestors, dests, func(m interface{}) {
+				*out = append(*out, m)
+			})
+		}
+
+		select {
+		case workers <- struct{}{}:
+			go func(c chan struct{}) {
+				defer func() { <-workers }()
+				run(c)
+			}(done[i])
+		default:
+			run(done[i])
+		}
+	}
+
+	for i := range objs {
+		<-done[i]
+		for _, m := range outs[i] {
+			report(m)
+		}
+	}
+}
+
+// cpHardLink creates to as a hard link to the earlier copy of from if links
+// are preserved, from has more than one link and from has already been
+// copied. It returns false if the file's data still needs to be copied, in
+// which case the returned function must be called once the data has been
+// copied.
+func cpHardLink(
+	from, to string,
+	fromFileInfo os.FileInfo,
+	report reporter) (bool, func()) {
+
+	if !preserve.links {
+		return false, func() {}
+	}
+
+	k, nlink, ok := attr.ID(fromFileInfo)
+	if !ok || nlink < 2 {
+		return false, func() {}
+	}
+
+	first, copied := copiedLinks.link(k, to)
+	if first == nil {
+		return false, copied
+	}
+
+	// another job may still be copying the first link
+	<-first.copied
+
+	if err := replace(to, func() error {
+		return os.Link(first.path, to)
+	}); err != nil {
+		report(err)
+		return true, nil
+	}
+
+	atomic.AddInt64(&copiedFiles, 1)
+	if verbose {
+		report(notice(fmt.Sprintf("%[1]s -> %[2]s", from, to)))
+	}
+	return true, nil
+}
+
+// cpSymlink creates the symbolic link to with the same target as the
+// symbolic link from.
+func cpSymlink(
+	from, to string, fromFileInfo os.FileInfo, report reporter) {
+
+	target, err := os.Readlink(from)
+	if err != nil {
+		report(err)
 		return
 	}
 
-	// if force is disabled and the destination path is a non-directory, then
-	// return an error
-	if !toFileInfo.IsDir() {
-		errc <- fmt.Sprintf("cp: %s is a non-directory (not copied)", to)
+	if err := replace(to, func() error {
+		return os.Symlink(target, to)
+	}); err != nil {
+		report(err)
 		return
 	}
 
-	// at this point we know we're copying something into a directory
-	cp(from, path.Join(to, path.Base(from)), errc)
+	if err := preserveAttrs(from, to, fromFileInfo); err != nil {
+		report(err)
+		return
+	}
+
+	atomic.AddInt64(&copiedFiles, 1)
+	if verbose {
+		report(notice(fmt.Sprintf("%[1]s -> %[2]s", from, to)))
+	}
 }
 
-func cpFileToFile(from, to string, errc chan interface{}) {
+// replace calls create to create the file to. If to already exists it is
+// removed and create is called again.
+func replace(to string, create func() error) error {
+	err := create()
+	if err == nil || !os.IsExist(err) {
+		return err
+	}
+	if err := os.Remove(to); err != nil {
+		return err
+	}
+	return create()
+}
+
+// rename renames the complete copy tmp to to. With -f, if to cannot be
+// replaced then it is removed and the rename is tried again. Windows does
+// not replace or remove read-only files, so to is made writable first.
+func rename(tmp, to string) error {
+	err := os.Rename(tmp, to)
+	if err == nil || !force {
+		return err
+	}
+	if fi, serr := os.Lstat(to); serr == nil && fi.Mode().Perm()&0200 == 0 {
+		os.Chmod(to, fi.Mode().Perm()|0200)
+	}
+	if rerr := os.Remove(to); rerr != nil && !os.IsNotExist(rerr) {
+		return err
+	}
+	return os.Rename(tmp, to)
+}
+
+// cpFileToFile copies the regular file from to to. The data is written to
+// a temporary file in the destination directory, which is renamed to to
+// once the copy is complete, so to is never left partially written.
+func cpFileToFile(
+	from, to string, fromFileInfo os.FileInfo, report reporter) {
+
 	fromFile, err := os.Open(from)
 	if err != nil {
-		errc <- err
+		report(err)
 		return
 	}
 
 	defer fromFile.Close()
 
-	toFile, err := os.Create(to)
+	// the new file gets the source file's permissions less the umask, just
+	// as POSIX requires of cp when the mode is not preserved
+	var toFile *os.File
+	if resume {
+		toFile, err = partial.Open(
+			to, fromFileInfo.Size(), fromFileInfo.Mode().Perm())
+	} else {
+		toFile, err = createTemp(to, fromFileInfo.Mode().Perm())
+	}
 	if err != nil {
-		errc <- err
+		report(err)
+		return
+	}
+
+	tmp := toFile.Name()
+	if err := cpData(toFile, fromFile, fromFileInfo); err != nil {
+		toFile.Close()
+		// a partial copy is kept so that it can be resumed later
+		if !resume {
+			os.Remove(tmp)
+		}
+		report(err)
 		return
 	}
 
-	defer toFile.Close()
+	if err := toFile.Close(); err != nil {
+		os.Remove(tmp)
+		report(err)
+		return
+	}
+
+	if resume {
+		if err := finishPartial(tmp, fromFileInfo.Mode().Perm()); err != nil {
+			report(err)
+			return
+		}
+	}
+
+	if err := preserveAttrs(from, tmp, fromFileInfo); err != nil {
+		os.Remove(tmp)
+		report(err)
+		return
+	}
 
-	if _, err := io.Copy(toFile, fromFile); err != nil {
-		errc <- err
+	if err := rename(tmp, to); err != nil {
+		os.Remove(tmp)
+		report(err)
 		return
 	}
 
+	atomic.AddInt64(&copiedFiles, 1)
 	if verbose {
-		fmt.Printf("%[1]s -> %[2]s\n", from, to)
+		report(notice(fmt.Sprintf("%[1]s -> %[2]s", from, to)))
+	}
+}
+
+// cpData copies the contents of from to the temporary file to, resuming
+// and verifying the copy if requested.
+func cpData(to, from *os.File, fromFileInfo os.FileInfo) error {
+	var off int64
+	if resume {
+		var err error
+		if off, err = partial.Resume(to, from, fromFileInfo.Size()); err != nil {
+			return err
+		}
+		atomic.AddInt64(&copiedBytes, off)
+	}
+
+	if off == 0 {
+		if err := copyData(to, from, fromFileInfo); err != nil {
+			return err
+		}
+	} else if _, err := io.Copy(&meter{w: to}, from); err != nil {
+		return err
+	}
+
+	if verify.value == "sha256" {
+		return partial.Verify(to, from, fromFileInfo.Size())
 	}
+	return nil
 }
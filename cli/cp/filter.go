@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/akutz/gnixutils/lib/os/partial"
+)
+
+var (
+	syncMode         bool
+	checksum         bool
+	deleteExtraneous bool
+
+	// filters are the --include and --exclude patterns in the order they
+	// were given. The first pattern that matches a path decides whether it
+	// is copied.
+	filters []filterRule
+)
+
+// filterRule is an --include or --exclude pattern. Patterns are shell globs
+// that match a file's name, or its path relative to the source directory
+// if the pattern contains a slash. A leading slash anchors the pattern to
+// the source directory and a trailing slash matches directories only.
+type filterRule struct {
+	pattern string
+	include bool
+}
+
+func (f filterRule) match(rel string, isDir bool) bool {
+	p := f.pattern
+	if strings.HasSuffix(p, "/") {
+		if !isDir {
+			return false
+		}
+		p = strings.TrimRight(p, "/")
+	}
+
+	if strings.HasPrefix(p, "/") {
+		ok, _ := path.Match(p[1:], rel)
+		return ok
+	}
+
+	if !strings.Contains(p, "/") {
+		ok, _ := path.Match(p, path.Base(rel))
+		return ok
+	}
+
+	// an unanchored pattern with a slash may match at any directory
+	for s := rel; ; {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+		i := strings.Index(s, "/")
+		if i < 0 {
+			return false
+		}
+		s = s[i+1:]
+	}
+}
+
+// filterFlag implements flag.Value for --include and --exclude, which may
+// both be given more than once.
+type filterFlag bool
+
+func (f filterFlag) String() string {
+	return ""
+}
+
+func (f filterFlag) Set(s string) error {
+	filters = append(filters, filterRule{pattern: s, include: bool(f)})
+	return nil
+}
+
+// excludeFromFlag implements flag.Value for --exclude-from. The file named
+// by the flag has one pattern per line; blank lines and lines that begin
+// with a # are ignored.
+type excludeFromFlag struct{}
+
+func (f excludeFromFlag) String() string {
+	return ""
+}
+
+func (f excludeFromFlag) Set(s string) error {
+	file, err := os.Open(s)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		filters = append(filters, filterRule{pattern: line})
+	}
+	return scanner.Err()
+}
+
+// excluded returns true if the file at rel, a path relative to the source
+// directory given on the command line, is excluded from the copy.
+func excluded(rel string, isDir bool) bool {
+	for _, f := range filters {
+		if f.match(rel, isDir) {
+			return !f.include
+		}
+	}
+	return false
+}
+
+// filterEntries returns the entries of the source directory at rel that
+// are not excluded from the copy.
+func filterEntries(rel string, objs []os.FileInfo) []os.FileInfo {
+	if len(filters) == 0 {
+		return objs
+	}
+	kept := objs[:0]
+	for _, o := range objs {
+		if !excluded(path.Join(rel, o.Name()), o.IsDir()) {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+// unchanged returns true if, in sync mode, the existing file to is already
+// a copy of from. Files are compared by size and modification time, or by
+// their checksums with --checksum.
+func unchanged(from, to string, fromFileInfo, toFileInfo os.FileInfo) bool {
+	if !syncMode || !fromFileInfo.Mode().IsRegular() ||
+		!toFileInfo.Mode().IsRegular() ||
+		fromFileInfo.Size() != toFileInfo.Size() {
+		return false
+	}
+
+	if !checksum {
+		return fromFileInfo.ModTime().Equal(toFileInfo.ModTime())
+	}
+
+	fromFile, err := os.Open(from)
+	if err != nil {
+		return false
+	}
+	defer fromFile.Close()
+	toFile, err := os.Open(to)
+	if err != nil {
+		return false
+	}
+	defer toFile.Close()
+	return partial.Verify(toFile, fromFile, fromFileInfo.Size()) == nil
+}
+
+// deleteEntries removes the entries of the destination directory to, the
+// copy of the source directory at rel, that are not among the names of
+// the source directory's entries. Excluded files are not removed.
+func deleteEntries(
+	to, rel string, objs []os.FileInfo, report reporter) {
+
+	names := map[string]bool{}
+	for _, o := range objs {
+		names[o.Name()] = true
+	}
+
+	toDir, err := os.Open(to)
+	if err != nil {
+		report(err)
+		return
+	}
+	toDirObjs, err := toDir.Readdir(-1)
+	toDir.Close()
+	if err != nil {
+		report(err)
+		return
+	}
+
+	for _, o := range toDirObjs {
+		if names[o.Name()] || excluded(path.Join(rel, o.Name()), o.IsDir()) {
+			continue
+		}
+		p := path.Join(to, o.Name())
+		if err := os.RemoveAll(p); err != nil {
+			report(err)
+			continue
+		}
+		atomic.AddInt64(&deletedFiles, 1)
+		if verbose {
+			report(notice(fmt.Sprintf("deleting %s", p)))
+		}
+	}
+}
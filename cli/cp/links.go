@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+)
+
+// derefMode describes when cp follows symbolic links in the source.
+type derefMode int
+
+const (
+	// derefDefault follows symbolic links unless copying recursively.
+	derefDefault derefMode = iota
+
+	// derefNever copies symbolic links as symbolic links (-P).
+	derefNever
+
+	// derefAlways copies the files symbolic links refer to (-L).
+	derefAlways
+
+	// derefArgs follows only the symbolic links given as operands (-H).
+	derefArgs
+)
+
+// derefFlag implements flag.Value for the mutually exclusive -P, -L and -H
+// flags. Whichever of them appears last on the command line wins.
+type derefFlag derefMode
+
+func (f derefFlag) String() string {
+	return "false"
+}
+
+func (f derefFlag) Set(s string) error {
+	if s != "true" {
+		return fmt.Errorf("invalid value %q", s)
+	}
+	deref = derefMode(f)
+	return nil
+}
+
+func (f derefFlag) IsBoolFlag() bool {
+	return true
+}
+
+// linkTarget is the first path a source file with more than one link was
+// copied to. The channel copied is closed once the copy is complete.
+type linkTarget struct {
+	path   string
+	copied chan struct{}
+}
+
+// hardLinks maps source files with more than one link to the first path
+// they were copied to.
+type hardLinks struct {
+	sync.Mutex
+	m map[attr.FileID]*linkTarget
+}
+
+var copiedLinks = &hardLinks{m: map[attr.FileID]*linkTarget{}}
+
+// link returns the first path the file with the ID k was copied to. If
+// the file has not been copied yet then to is recorded as that path, and
+// link returns nil and a function to call once the copy is complete.
+func (h *hardLinks) link(
+	k attr.FileID, to string) (*linkTarget, func()) {
+
+	h.Lock()
+	defer h.Unlock()
+	if t, ok := h.m[k]; ok {
+		return t, nil
+	}
+	t := &linkTarget{path: to, copied: make(chan struct{})}
+	h.m[k] = t
+	return nil, func() { close(t.copied) }
+}
+
+// statSource returns the file info for a source path. Operands are the
+// paths given on the command line.
+func statSource(p string, operand bool) (os.FileInfo, error) {
+	if deref == derefAlways || (deref == derefArgs && operand) {
+		return os.Stat(p)
+	}
+	return os.Lstat(p)
+}
+
+// isCycle returns true if the directory described by fi is one of the
+// directories with the IDs ancestors, such as the directories currently
+// being copied.
+func isCycle(fi os.FileInfo, ancestors []attr.FileID) bool {
+	k, _, ok := attr.ID(fi)
+	if !ok {
+		return false
+	}
+	for _, a := range ancestors {
+		if a == k {
+			return true
+		}
+	}
+	return false
+}
+
+// destAncestors returns the IDs of the directory that contains the
+// destination to and of each of its ancestors. ".." is followed rather than
+// the path shortened, so that symbolic links in to are resolved.
+func destAncestors(to string) []attr.FileID {
+	ids := []attr.FileID{}
+	for p := path.Dir(to); ; p += "/.." {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return ids
+		}
+		k, _, ok := attr.ID(fi)
+		if !ok || (len(ids) > 0 && ids[len(ids)-1] == k) {
+			return ids
+		}
+		ids = append(ids, k)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/akutz/gnixutils/lib/os/backup"
+)
+
+var (
+	promptLock  sync.Mutex
+	promptInput = bufio.NewReader(os.Stdin)
+)
+
+// overwrite decides whether the existing non-directory to may be replaced
+// by a copy of from, and backs it up if backups are enabled.
+func overwrite(
+	from, to string,
+	fromFileInfo, toFileInfo os.FileInfo,
+	report reporter) bool {
+
+	ok := replaceable(from, to, fromFileInfo, toFileInfo)
+	if !ok {
+		atomic.AddInt64(&skippedFiles, 1)
+		return false
+	}
+
+	name, err := backup.Make(to, backupControl, backupSuffix)
+	if err != nil {
+		report(err)
+		return false
+	}
+	if verbose && name != "" {
+		report(notice(fmt.Sprintf("%s -> %s (backup)", to, name)))
+	}
+
+	return true
+}
+
+// replaceable applies -n, -u and -i to the existing file to.
+func replaceable(
+	from, to string, fromFileInfo, toFileInfo os.FileInfo) bool {
+
+	if noClobber {
+		return false
+	}
+
+	if update && !fromFileInfo.ModTime().After(toFileInfo.ModTime()) {
+		return false
+	}
+
+	if interactive && !confirm(fmt.Sprintf("cp: overwrite %s? ", to)) {
+		return false
+	}
+
+	return true
+}
+
+// confirm writes the prompt to stderr and returns true if the answer read
+// from stdin begins with a y.
+func confirm(prompt string) bool {
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	fmt.Fprint(os.Stderr, prompt)
+	answer, _ := promptInput.ReadString('\n')
+	return strings.HasPrefix(strings.ToLower(answer), "y")
+}
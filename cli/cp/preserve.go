@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+)
+
+// attrs is the set of file attributes preserved by cp. It implements
+// flag.Value so that it can be given as --preserve or --preserve=LIST.
+type attrs struct {
+	mode       bool
+	ownership  bool
+	timestamps bool
+	xattr      bool
+	links      bool
+}
+
+func (a *attrs) setDefault() {
+	a.mode = true
+	a.ownership = true
+	a.timestamps = true
+}
+
+func (a *attrs) setAll() {
+	a.setDefault()
+	a.xattr = true
+	a.links = true
+}
+
+func (a *attrs) String() string {
+	if a == nil {
+		return ""
+	}
+	names := []string{}
+	if a.mode {
+		names = append(names, "mode")
+	}
+	if a.ownership {
+		names = append(names, "ownership")
+	}
+	if a.timestamps {
+		names = append(names, "timestamps")
+	}
+	if a.xattr {
+		names = append(names, "xattr")
+	}
+	if a.links {
+		names = append(names, "links")
+	}
+	return strings.Join(names, ",")
+}
+
+// Set parses a comma-separated list of attributes. The value "true" is
+// what the flag package passes when --preserve is given without a list.
+func (a *attrs) Set(s string) error {
+	if s == "true" {
+		a.setDefault()
+		return nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		switch name {
+		case "mode":
+			a.mode = true
+		case "ownership":
+			a.ownership = true
+		case "timestamps":
+			a.timestamps = true
+		case "xattr":
+			a.xattr = true
+		case "links":
+			a.links = true
+		case "all":
+			a.setAll()
+		default:
+			return fmt.Errorf("invalid attribute %q", name)
+		}
+	}
+	return nil
+}
+
+func (a *attrs) IsBoolFlag() bool {
+	return true
+}
+
+// preserveAttrs applies the attributes of the source file, described by
+// fi, to the copy at the path to. Ownership is applied before the mode
+// since changing a file's owner clears its set-user-ID and set-group-ID
+// bits, and the timestamps are applied last.
+func preserveAttrs(from, to string, fi os.FileInfo) error {
+	isLink := fi.Mode()&os.ModeSymlink != 0
+
+	if preserve.ownership {
+		if err := attr.Lchown(to, fi); err != nil {
+			return err
+		}
+	}
+
+	if preserve.mode && !isLink {
+		if err := os.Chmod(to, fi.Mode()); err != nil {
+			return err
+		}
+	}
+
+	if preserve.xattr {
+		if err := attr.CopyXattrs(from, to, isLink); err != nil {
+			return err
+		}
+	}
+
+	if preserve.timestamps {
+		if err := attr.SetTimes(to, fi); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// isSparse returns true if the file described by fi occupies fewer blocks
+// than its size requires, which means it has holes.
+func isSparse(fi os.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return int64(st.Blocks)*512 < st.Size
+}
@@ -0,0 +1,9 @@
+package main
+
+import (
+	"os"
+)
+
+func isSparse(fi os.FileInfo) bool {
+	return false
+}
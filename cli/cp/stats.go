@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+)
+
+var (
+	showProgress bool
+	showStats    bool
+	bwlimit      rate
+
+	// the totals of the sources found by the pre-scan for --progress
+	totalFiles int64
+	totalBytes int64
+
+	copiedFiles  int64
+	copiedBytes  int64
+	skippedFiles int64
+	deletedFiles int64
+	failedFiles  int64
+
+	// startTime is when copying began, after any pre-scan, so that the
+	// time spent scanning is not counted toward the bandwidth limit
+	startTime time.Time
+
+	limiter struct {
+		sync.Mutex
+		bytes int64
+	}
+)
+
+// rate is a transfer rate in bytes per second. It implements flag.Value
+// and, as with rsync's --bwlimit, is given in KiB unless it has one of the
+// suffixes K, M or G.
+type rate int64
+
+func (r *rate) String() string {
+	if r == nil || *r == 0 {
+		return ""
+	}
+	return formatBytes(int64(*r)) + "/s"
+}
+
+func (r *rate) Set(s string) error {
+	mult := int64(1024)
+	if n := len(s); n > 0 {
+		switch strings.ToUpper(s[n-1:]) {
+		case "K":
+			s = s[:n-1]
+		case "M":
+			mult, s = 1024*1024, s[:n-1]
+		case "G":
+			mult, s = 1024*1024*1024, s[:n-1]
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid rate %q", s)
+	}
+	*r = rate(v * float64(mult))
+	return nil
+}
+
+// startCopy marks the start of the copy for the bandwidth limit, the
+// progress and the statistics.
+func startCopy() {
+	startTime = time.Now()
+}
+
+// transferred records that n bytes of file data were copied. If the
+// bandwidth is limited it blocks until the copy is back within the limit.
+func transferred(n int64) {
+	defer atomic.AddInt64(&copiedBytes, n)
+
+	if bwlimit <= 0 {
+		return
+	}
+
+	limiter.Lock()
+	limiter.bytes += n
+	due := startTime.Add(time.Duration(
+		float64(limiter.bytes) / float64(bwlimit) * float64(time.Second)))
+	limiter.Unlock()
+
+	if d := due.Sub(time.Now()); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// chunkSize returns the most data that is copied before the bandwidth
+// limit is applied. With --bwlimit a chunk is a tenth of a second's worth
+// of data, so the copy runs at a steady rate rather than in bursts.
+func chunkSize() int64 {
+	n := int64(copyChunkSize)
+	if bwlimit > 0 && int64(bwlimit)/10 < n {
+		n = int64(bwlimit) / 10
+		if n < 1 {
+			n = 1
+		}
+	}
+	return n
+}
+
+// meter is an io.Writer that records the bytes written to w as
+// transferred. Writes are split into chunks of chunkSize bytes so that the
+// bandwidth limit is applied to each of them.
+type meter struct {
+	w io.Writer
+}
+
+func (m *meter) Write(p []byte) (int, error) {
+	c := int(chunkSize())
+	var written int
+	for len(p) > 0 {
+		b := p
+		if len(b) > c {
+			b = b[:c]
+		}
+		n, err := m.w.Write(b)
+		transferred(int64(n))
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// scan adds the files and bytes that copying p, found at rel in a source
+// given on the command line, will copy to the totals shown by --progress.
+func scan(p, rel string, ancestors []attr.FileID) {
+	fi, err := statSource(p, ancestors == nil)
+	if err != nil {
+		return
+	}
+
+	if !fi.IsDir() {
+		totalFiles++
+		if fi.Mode().IsRegular() {
+			totalBytes += fi.Size()
+		}
+		return
+	}
+
+	if !recursive || isCycle(fi, ancestors) {
+		return
+	}
+	if k, _, ok := attr.ID(fi); ok {
+		ancestors = append(ancestors[:len(ancestors):len(ancestors)], k)
+	} else if ancestors == nil {
+		ancestors = []attr.FileID{}
+	}
+
+	d, err := os.Open(p)
+	if err != nil {
+		return
+	}
+	objs, err := d.Readdir(-1)
+	d.Close()
+	if err != nil {
+		return
+	}
+	for _, o := range filterEntries(rel, objs) {
+		scan(path.Join(p, o.Name()), path.Join(rel, o.Name()), ancestors)
+	}
+}
+
+// progress writes the progress of the copy to stderr every interval until
+// done is closed.
+func progress(interval time.Duration, done chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			fmt.Fprintf(os.Stderr, "\r%s\033[K", progressLine())
+		case <-done:
+			fmt.Fprintf(os.Stderr, "\r%s\033[K\n", progressLine())
+			return
+		}
+	}
+}
+
+func progressLine() string {
+	bytes := atomic.LoadInt64(&copiedBytes)
+	files := atomic.LoadInt64(&copiedFiles)
+	elapsed := time.Since(startTime)
+
+	var speed float64
+	if elapsed > 0 {
+		speed = float64(bytes) / elapsed.Seconds()
+	}
+
+	// files may grow while they are copied
+	pct := int64(100)
+	if totalBytes > 0 && bytes < totalBytes {
+		pct = bytes * 100 / totalBytes
+	}
+
+	eta := "--:--:--"
+	if speed > 0 && bytes <= totalBytes {
+		eta = formatDuration(time.Duration(
+			float64(totalBytes-bytes) / speed * float64(time.Second)))
+	}
+
+	return fmt.Sprintf("%s / %s (%d%%)  %d/%d files  %s/s  ETA %s",
+		formatBytes(bytes), formatBytes(totalBytes), pct,
+		files, totalFiles,
+		formatBytes(int64(speed)), eta)
+}
+
+// printStats writes the summary of the copy shown by --stats.
+func printStats() {
+	fmt.Printf("Files copied: %d\n", atomic.LoadInt64(&copiedFiles))
+	fmt.Printf("Bytes copied: %d\n", atomic.LoadInt64(&copiedBytes))
+	fmt.Printf("Files skipped: %d\n", atomic.LoadInt64(&skippedFiles))
+	if deleteExtraneous {
+		fmt.Printf("Files deleted: %d\n", atomic.LoadInt64(&deletedFiles))
+	}
+	fmt.Printf("Files failed: %d\n", atomic.LoadInt64(&failedFiles))
+	fmt.Printf("Elapsed: %s\n", formatDuration(time.Since(startTime)))
+}
+
+func formatBytes(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	v := float64(n)
+	i := -1
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f%ciB", v, units[i])
+}
+
+func formatDuration(d time.Duration) string {
+	s := int64(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
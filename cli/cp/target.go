@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	targetDir   string
+	noTargetDir bool
+	parents     bool
+
+	// trailingSlash selects how a source directory with a trailing slash
+	// is copied into an existing directory. GNU cp copies the directory
+	// itself, while BSD cp copies the directory's contents.
+	trailingSlash = &choice{
+		value:   "gnu",
+		choices: []string{"gnu", "bsd"},
+	}
+)
+
+func hasTrailingSlash(p string) bool {
+	if len(p) < 2 {
+		return false
+	}
+	c := p[len(p)-1]
+	return c == '/' || c == os.PathSeparator
+}
+
+// destination returns the path the source from is copied to. The path to
+// is the destination operand; toIsDir is true if the sources are copied
+// into it rather than to it.
+func destination(from, to string, toIsDir bool) string {
+	switch {
+	case !toIsDir:
+		return to
+	case parents:
+		return path.Join(to, from)
+	case trailingSlash.value == "bsd" && hasTrailingSlash(from):
+		return path.Clean(to)
+	}
+	return path.Join(to, path.Base(from))
+}
+
+// mkParents creates the directories in the destination directory to that
+// correspond to the parent directories of the source from. The directories
+// are created with the permissions of the source directories.
+func mkParents(from, to string) error {
+	dir := path.Dir(path.Clean(from))
+	if dir == "." || dir == "/" {
+		return nil
+	}
+
+	src := ""
+	if path.IsAbs(dir) {
+		src = "/"
+	}
+
+	for _, name := range strings.Split(strings.Trim(dir, "/"), "/") {
+		src = path.Join(src, name)
+		dst := path.Join(to, src)
+
+		if _, err := os.Stat(dst); err == nil {
+			continue
+		}
+
+		fi, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Mkdir(dst, fi.Mode().Perm()|0700); err != nil {
+			return err
+		}
+		if err := preserveAttrs(src, dst, fi); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+var (
+	resume bool
+
+	verify = &choice{
+		value:   "none",
+		choices: []string{"none", "sha256"},
+	}
+
+	// tempRand is shared by the jobs creating temporary files, and a
+	// rand.Rand is not safe for concurrent use
+	tempRand = struct {
+		sync.Mutex
+		*rand.Rand
+	}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+)
+
+// createTemp creates a new file in the same directory as to with the
+// permissions perm less the umask. The copy of a file is written to the
+// temporary file and renamed to to once it is complete.
+func createTemp(to string, perm os.FileMode) (*os.File, error) {
+	dir, base := path.Split(to)
+	for {
+		name := path.Join(
+			dir, fmt.Sprintf(".%s.cp-%06d", base, tempSuffix()))
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_RDWR, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
+	}
+}
+
+// tempSuffix returns a random number for the name of a temporary file.
+func tempSuffix() int {
+	tempRand.Lock()
+	defer tempRand.Unlock()
+	return tempRand.Intn(1000000)
+}
+
+// finishPartial gives the complete partial copy p the permissions perm less
+// the umask, as a new file would have been given.
+func finishPartial(p string, perm os.FileMode) error {
+	return os.Chmod(p, perm&^umask)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/group"
+	"github.com/akutz/gnixutils/lib/os/user"
+)
+
+var (
+	onlyUser   bool
+	onlyGroup  bool
+	onlyGroups bool
+	names      bool
+	realIDs    bool
+
+	// hasErrs is set when a name cannot be found.
+	hasErrs bool
+)
+
+func init() {
+	flag.BoolVar(&onlyUser, "u", false, "Print only the effective user ID")
+	flag.BoolVar(&onlyGroup, "g", false, "Print only the effective group ID")
+	flag.BoolVar(&onlyGroups, "G", false, "Print all group IDs")
+	flag.BoolVar(&names, "n", false,
+		"Print a name instead of a number, for -u, -g or -G")
+	flag.BoolVar(&realIDs, "r", false,
+		"Print the real ID instead of the effective ID, with -u, -g or -G")
+}
+
+// ids are the user and group IDs that id prints.
+type ids struct {
+	uid, gid   int
+	euid, egid int
+	groups     []int
+}
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+
+	only := 0
+	for _, b := range []bool{onlyUser, onlyGroup, onlyGroups} {
+		if b {
+			only++
+		}
+	}
+	if only > 1 {
+		fmt.Println("id: cannot print \"only\" of more than one choice")
+		os.Exit(1)
+	}
+	if only == 0 && (names || realIDs) {
+		fmt.Println(
+			"id: cannot print only names or real IDs in default format")
+		os.Exit(1)
+	}
+	if len(args) > 1 {
+		fmt.Printf("id: extra operand '%s'\n", args[1])
+		os.Exit(1)
+	}
+
+	var i *ids
+	var err error
+	if len(args) == 1 {
+		i, err = userIDs(args[0])
+	} else {
+		i, err = processIDs()
+	}
+	if err != nil {
+		fmt.Printf("id: %s\n", err)
+		os.Exit(1)
+	}
+
+	uid, gid := i.euid, i.egid
+	if realIDs {
+		uid, gid = i.uid, i.gid
+	}
+
+	switch {
+	case onlyUser:
+		fmt.Println(id(uid, userName))
+	case onlyGroup:
+		fmt.Println(id(gid, groupName))
+	case onlyGroups:
+		var ss []string
+		for _, g := range i.groups {
+			ss = append(ss, id(g, groupName))
+		}
+		fmt.Println(strings.Join(ss, " "))
+	default:
+		fmt.Println(i)
+	}
+
+	if hasErrs {
+		os.Exit(1)
+	}
+}
+
+// userIDs returns the IDs of the user with the given name or numeric ID.
+func userIDs(name string) (*ids, error) {
+	usr, err := user.LookupUser(name)
+	if err != nil {
+		if _, perr := strconv.Atoi(name); perr != nil {
+			return nil, fmt.Errorf("'%s': no such user", name)
+		}
+		if usr, err = user.LookupUserID(name); err != nil {
+			return nil, fmt.Errorf("'%s': no such user", name)
+		}
+	}
+
+	i := &ids{}
+	i.uid, _ = strconv.Atoi(usr.ID)
+	i.gid, _ = strconv.Atoi(usr.GroupID)
+	i.euid, i.egid = i.uid, i.gid
+
+	gids, err := group.GroupIDsForUser(usr.Username)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get groups for '%s': %s", name, err)
+	}
+	for _, s := range gids {
+		g, _ := strconv.Atoi(s)
+		i.groups = appendID(i.groups, g)
+	}
+	return i, nil
+}
+
+// processIDs returns the IDs of the current process. As with GNU id, its
+// groups are the real and effective group IDs followed by the
+// supplementary group IDs.
+func processIDs() (*ids, error) {
+	i := &ids{
+		uid:  os.Getuid(),
+		gid:  os.Getgid(),
+		euid: os.Geteuid(),
+		egid: os.Getegid(),
+	}
+
+	gids, err := os.Getgroups()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get groups: %s", err)
+	}
+	i.groups = appendID(i.groups, i.gid)
+	i.groups = appendID(i.groups, i.egid)
+	for _, g := range gids {
+		i.groups = appendID(i.groups, g)
+	}
+	return i, nil
+}
+
+// appendID appends n to list unless it is already there.
+func appendID(list []int, n int) []int {
+	for _, m := range list {
+		if m == n {
+			return list
+		}
+	}
+	return append(list, n)
+}
+
+// String formats i as id does by default, such as
+// uid=0(root) gid=0(root) groups=0(root). The effective IDs are included
+// only if they differ from the real IDs.
+func (i *ids) String() string {
+	s := "uid=" + withName(i.uid, userName)
+	s += " gid=" + withName(i.gid, groupName)
+	if i.euid != i.uid {
+		s += " euid=" + withName(i.euid, userName)
+	}
+	if i.egid != i.gid {
+		s += " egid=" + withName(i.egid, groupName)
+	}
+	var ss []string
+	for _, g := range i.groups {
+		ss = append(ss, withName(g, groupName))
+	}
+	return s + " groups=" + strings.Join(ss, ",")
+}
+
+// id formats n as a number, or with -n as the name returned by lookup. The
+// number is used if there is no such name.
+func id(n int, lookup func(int) (string, bool)) string {
+	if !names {
+		return strconv.Itoa(n)
+	}
+	name, ok := lookup(n)
+	if !ok {
+		hasErrs = true
+		return strconv.Itoa(n)
+	}
+	return name
+}
+
+// withName formats n followed by its name in parentheses, such as 0(root),
+// or as just n if it has no name.
+func withName(n int, lookup func(int) (string, bool)) string {
+	s := strconv.Itoa(n)
+	if name, ok := lookup(n); ok {
+		s += "(" + name + ")"
+	}
+	return s
+}
+
+func userName(uid int) (string, bool) {
+	usr, err := user.LookupUserID(strconv.Itoa(uid))
+	if err != nil || usr.Username == "" {
+		if names {
+			fmt.Fprintf(os.Stderr,
+				"id: cannot find name for user ID %d\n", uid)
+		}
+		return "", false
+	}
+	return usr.Username, true
+}
+
+func groupName(gid int) (string, bool) {
+	grp, err := group.LookupGroupID(strconv.Itoa(gid))
+	if err != nil || grp.Name == "" {
+		if names {
+			fmt.Fprintf(os.Stderr,
+				"id: cannot find name for group ID %d\n", gid)
+		}
+		return "", false
+	}
+	return grp.Name, true
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// selinuxEnabled returns true if the SELinux file system is mounted, which
+// it is when the kernel enforces an SELinux policy.
+func selinuxEnabled() bool {
+	_, err := os.Stat("/sys/fs/selinux/enforce")
+	return err == nil
+}
+
+// setContext sets the SELinux security context of p to ctx.
+func setContext(p, ctx string) error {
+	// the kernel expects the context to be terminated like a C string
+	err := unix.Lsetxattr(p, "security.selinux", []byte(ctx+"\x00"), 0)
+	if err != nil {
+		return &os.PathError{Op: "setxattr", Path: p, Err: err}
+	}
+	return nil
+}
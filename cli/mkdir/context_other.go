@@ -0,0 +1,12 @@
+// +build !linux
+
+package main
+
+// selinuxEnabled returns false; SELinux is only available on Linux.
+func selinuxEnabled() bool {
+	return false
+}
+
+func setContext(p, ctx string) error {
+	return nil
+}
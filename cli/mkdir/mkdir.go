@@ -4,33 +4,189 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/mode"
 )
 
+// contextFlag implements flag.Value for --context[=CTX]. Given without a
+// value it is the same as -Z.
+type contextFlag string
+
+func (f *contextFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return string(*f)
+}
+
+func (f *contextFlag) Set(s string) error {
+	if s == "true" {
+		defaultContext = true
+		return nil
+	}
+	*f = contextFlag(s)
+	return nil
+}
+
+func (f *contextFlag) IsBoolFlag() bool {
+	return true
+}
+
 var (
-	paths bool
+	paths          bool
+	modeArg        string
+	verbose        bool
+	defaultContext bool
+	context        contextFlag
+
+	umask os.FileMode
+
+	// dirMode is the mode of the directories named on the command line and
+	// parentMode that of the parent directories created by -p.
+	dirMode    os.FileMode
+	parentMode os.FileMode
 )
 
 func init() {
 	flag.BoolVar(&paths, "p", false,
 		"Create intermediate directories as required.")
+	flag.StringVar(&modeArg, "m", "",
+		"Set the file mode of the directories, as in chmod, instead of "+
+			"a=rwx minus the umask.")
+	flag.BoolVar(&verbose, "v", false,
+		"Print a message for each created directory.")
+	flag.BoolVar(&defaultContext, "Z", false,
+		"Give the directories the default SELinux security context.")
+	flag.Var(&context, "context",
+		"Like -Z, or if CTX is given then set the SELinux security "+
+			"context of the directories to CTX.")
 }
 
 func main() {
 	flag.Parse()
+
+	umask = mode.Umask()
+	dirMode = 0777 &^ umask
+	parentMode = dirMode | 0300
+
+	if modeArg != "" {
+		m, err := mode.Parse(modeArg)
+		if err != nil {
+			fmt.Printf("mkdir: %s\n", err)
+			os.Exit(1)
+		}
+		dirMode = m.Apply(os.ModeDir|0777, umask) &^ os.ModeDir
+	}
+
+	if (defaultContext || context != "") && !selinuxEnabled() {
+		fmt.Fprintln(os.Stderr, "mkdir: warning: ignoring --context; "+
+			"it requires an SELinux-enabled kernel")
+		defaultContext, context = false, ""
+	}
+
+	hasErrs := false
 	for _, p := range flag.Args() {
-		mkdir(p)
+		if !mkdir(p) {
+			hasErrs = true
+		}
+	}
+
+	if hasErrs {
+		os.Exit(1)
 	}
 }
 
-func mkdir(p string) {
-	var err error
+// mkdir creates the directory p, and its parents with -p. It returns false
+// if it fails.
+func mkdir(p string) bool {
 	if paths {
-		err = os.MkdirAll(p, 0755)
-	} else {
-		err = os.Mkdir(p, 0755)
+		if !mkParents(p) {
+			return false
+		}
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			return true
+		}
 	}
-	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
+
+	if err := os.Mkdir(p, dirMode); err != nil {
+		printError(p, err)
+		return false
+	}
+
+	// the umask has been applied to the mode given to Mkdir, and
+	// special bits are not set by it on every platform
+	if modeArg != "" {
+		if err := os.Chmod(p, dirMode); err != nil {
+			printError(p, err)
+			return false
+		}
+	}
+
+	return created(p)
+}
+
+// mkParents creates the missing parent directories of p. As with GNU
+// mkdir they are given u+wx on top of the default mode so that their
+// children can be created.
+func mkParents(p string) bool {
+	var missing []string
+	for d := path.Dir(path.Clean(p)); ; d = path.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if d == path.Dir(d) {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		d := missing[i]
+		if err := os.Mkdir(d, parentMode); err != nil {
+			if fi, serr := os.Stat(d); serr == nil && fi.IsDir() {
+				continue
+			}
+			printError(d, err)
+			return false
+		}
+		if parentMode != 0777&^umask {
+			if err := os.Chmod(d, parentMode); err != nil {
+				printError(d, err)
+				return false
+			}
+		}
+		if !created(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// created sets the security context of the new directory p and reports
+// its creation with -v.
+func created(p string) bool {
+	if context != "" {
+		if err := setContext(p, string(context)); err != nil {
+			printError(p, err)
+			return false
+		}
+	}
+	if verbose {
+		fmt.Printf("mkdir: created directory '%s'\n", p)
+	}
+	return true
+}
+
+// printError prints the error err that occurred while creating p.
+func printError(p string, err error) {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	msg := err.Error()
+	if msg != "" {
+		msg = strings.ToUpper(msg[:1]) + msg[1:]
 	}
+	fmt.Printf("mkdir: %s: %s\n", p, msg)
 }
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// writableDir returns an error if the effective user cannot write to and
+// search the directory dir. AIX has no AT_EACCESS, and access(2) checks the
+// real user, so when the real and effective IDs differ the check is left
+// to the removal itself.
+func writableDir(dir string) error {
+	if os.Getuid() != os.Geteuid() || os.Getgid() != os.Getegid() {
+		return nil
+	}
+	return unix.Access(dir, unix.W_OK|unix.X_OK)
+}
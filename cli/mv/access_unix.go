@@ -0,0 +1,15 @@
+// +build !aix,!windows
+
+package main
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// writableDir returns an error if the effective user cannot write to and
+// search the directory dir. Unlike access(2), which checks the real user,
+// this gives the right answer when mv runs with other effective IDs.
+func writableDir(dir string) error {
+	return unix.Faccessat(
+		unix.AT_FDCWD, dir, unix.W_OK|unix.X_OK, unix.AT_EACCESS)
+}
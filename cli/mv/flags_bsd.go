@@ -0,0 +1,24 @@
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// the file flags of chflags that prevent a file from being removed
+const (
+	ufImmutable = 0x2
+	ufAppend    = 0x4
+	sfImmutable = 0x20000
+	sfAppend    = 0x40000
+)
+
+// immutable returns true if p, described by fi, has the immutable or
+// append-only flag.
+func immutable(p string, fi os.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok &&
+		st.Flags&(ufImmutable|ufAppend|sfImmutable|sfAppend) != 0
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// the inode flags of chattr that prevent a file from being removed
+const (
+	fsImmutableFl = 0x10
+	fsAppendFl    = 0x20
+)
+
+// immutable returns true if the regular file or directory p, described by
+// fi, has the immutable or append-only attribute. Files that cannot be
+// opened are assumed not to.
+func immutable(p string, fi os.FileInfo) bool {
+	if !fi.Mode().IsRegular() && !fi.IsDir() {
+		return false
+	}
+	fd, err := unix.Open(
+		p, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return false
+	}
+	defer unix.Close(fd)
+
+	flags, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	return err == nil && flags&(fsImmutableFl|fsAppendFl) != 0
+}
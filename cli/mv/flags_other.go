@@ -0,0 +1,11 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package main
+
+import (
+	"os"
+)
+
+func immutable(p string, fi os.FileInfo) bool {
+	return false
+}
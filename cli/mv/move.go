@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+	"github.com/akutz/gnixutils/lib/os/walk"
+)
+
+// move renames from to to. If they are on different file systems then from
+// is copied to to, preserving its attributes, symbolic links and hard
+// links, and is removed once the copy is complete. If noReplace is true
+// and to exists then an error satisfying os.IsExist is returned.
+func move(from, to string, noReplace bool) error {
+	rename := os.Rename
+	if noReplace {
+		rename = renameNoReplace
+	}
+	err := rename(from, to)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+	return moveAcross(from, to, rename)
+}
+
+// statAndRename renames from to to unless to exists. It is used where the
+// file system cannot check for the target atomically.
+func statAndRename(from, to string) error {
+	if _, err := os.Lstat(to); err == nil {
+		return &os.LinkError{
+			Op: "rename", Old: from, New: to, Err: syscall.EEXIST}
+	}
+	return os.Rename(from, to)
+}
+
+// moveAcross copies from to a temporary path beside to and renames the copy
+// to to once it is complete, so a failed copy is removed rather than left
+// behind as a partial destination alongside the intact source. The copy is
+// renamed with rename. Nothing is copied unless from can be removed
+// afterwards, so that a complete destination is not left beside a partly
+// removed source.
+func moveAcross(from, to string, rename func(string, string) error) error {
+	fi, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if err := checkRemovable(from, fi); err != nil {
+		return err
+	}
+
+	dir, base := path.Split(to)
+	tmp := path.Join(dir, fmt.Sprintf(".%s.mv-%d", base, os.Getpid()))
+
+	c := &copier{links: map[attr.FileID]string{}}
+	if err := c.copy(from, tmp, fi); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+
+	if err := rename(tmp, to); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+
+	if err := os.RemoveAll(from); err != nil {
+		return &removeError{from: from, to: to, left: leftovers(from), err: err}
+	}
+	return nil
+}
+
+// maxLeftovers is the most leftover source paths a removeError names.
+const maxLeftovers = 10
+
+// removeError is returned when a source has been moved across file systems
+// but could not be removed afterwards.
+type removeError struct {
+	from, to string
+	left     []string
+	err      error
+}
+
+func (e *removeError) Error() string {
+	s := fmt.Sprintf(
+		"mv: %s is a complete copy of %s, but the source could not be "+
+			"removed: %s", e.to, e.from, e.err)
+	if len(e.left) == 0 {
+		return s
+	}
+	names := e.left
+	if len(names) > maxLeftovers {
+		names = names[:maxLeftovers]
+	}
+	s += "\nmv: left behind: " + strings.Join(names, ", ")
+	if n := len(e.left) - len(names); n > 0 {
+		s += fmt.Sprintf(" and %d more", n)
+	}
+	return s
+}
+
+// leftovers returns the paths that remain in the tree from.
+func leftovers(from string) []string {
+	var left []string
+	walk.Walk(from, walk.Physical, func(
+		p string, fi os.FileInfo, err error) error {
+
+		if err == nil {
+			left = append(left, p)
+		}
+		return nil
+	})
+	return left
+}
+
+// copier copies a file tree for a move across file systems.
+type copier struct {
+	// links maps the files in the tree with more than one link to the
+	// first path they were copied to
+	links map[attr.FileID]string
+}
+
+func (c *copier) copy(from, to string, fi os.FileInfo) error {
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(from)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, to); err != nil {
+			return err
+		}
+	case fi.IsDir():
+		if err := c.copyDir(from, to); err != nil {
+			return err
+		}
+	case fi.Mode().IsRegular():
+		if id, nlink, ok := attr.ID(fi); ok && nlink > 1 {
+			if first, ok := c.links[id]; ok {
+				return os.Link(first, to)
+			}
+			c.links[id] = to
+		}
+		if err := copyFile(from, to); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf(
+			"mv: %s: cannot move special file across file systems", from)
+	}
+
+	return preserveAttrs(from, to, fi)
+}
+
+// copyDir copies the directory from and its contents to the new directory
+// to. The directory's attributes are applied by the caller once its
+// contents have been copied.
+func (c *copier) copyDir(from, to string) error {
+	if err := os.Mkdir(to, 0700); err != nil {
+		return err
+	}
+
+	d, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	objs, err := d.Readdir(-1)
+	d.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, o := range objs {
+		err := c.copy(path.Join(from, o.Name()), path.Join(to, o.Name()), o)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	fromFile, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer fromFile.Close()
+
+	toFile, err := os.OpenFile(to, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(toFile, fromFile); err != nil {
+		toFile.Close()
+		return err
+	}
+	return toFile.Close()
+}
+
+// preserveAttrs applies all of the attributes of from, described by fi, to
+// its copy to.
+func preserveAttrs(from, to string, fi os.FileInfo) error {
+	isLink := fi.Mode()&os.ModeSymlink != 0
+
+	if err := attr.Lchown(to, fi); err != nil {
+		return err
+	}
+	if !isLink {
+		if err := os.Chmod(to, fi.Mode()); err != nil {
+			return err
+		}
+	}
+	if err := attr.CopyXattrs(from, to, isLink); err != nil {
+		return err
+	}
+	return attr.SetTimes(to, fi)
+}
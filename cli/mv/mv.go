@@ -1,34 +1,215 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"os"
+	"path"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/backup"
+)
+
+// overwriteMode describes what mv does when a target file exists.
+type overwriteMode int
+
+const (
+	// overwriteForce replaces existing files without prompting (-f).
+	overwriteForce overwriteMode = iota
+
+	// overwritePrompt asks before replacing existing files (-i).
+	overwritePrompt
+
+	// overwriteNever does not replace existing files (-n).
+	overwriteNever
 )
 
+// overwriteFlag implements flag.Value for the mutually exclusive -f, -i
+// and -n flags. Whichever of them appears last on the command line wins.
+type overwriteFlag overwriteMode
+
+func (f overwriteFlag) String() string {
+	return "false"
+}
+
+func (f overwriteFlag) Set(s string) error {
+	if s != "true" {
+		return fmt.Errorf("invalid value %q", s)
+	}
+	overwrite = overwriteMode(f)
+	return nil
+}
+
+func (f overwriteFlag) IsBoolFlag() bool {
+	return true
+}
+
 var (
-	doNotOverwrite bool
+	overwrite     overwriteMode
+	swap          bool
+	update        bool
+	verbose       bool
+	targetDir     string
+	noTargetDir   bool
+	simpleBackup  bool
+	backupControl backup.Control
+	backupSuffix  string
+
+	stdin = bufio.NewReader(os.Stdin)
 )
 
 func init() {
-	flag.BoolVar(&doNotOverwrite, "n", false,
+	flag.Var(overwriteFlag(overwriteForce), "f",
+		"Do not prompt before overwriting an existing file.")
+	flag.Var(overwriteFlag(overwritePrompt), "i",
+		"Prompt before overwriting an existing file.")
+	flag.Var(overwriteFlag(overwriteNever), "n",
 		"Do not overwrite an existing file.")
+	flag.BoolVar(&swap, "exchange", false,
+		"Atomically exchange the source and target, which must both exist.")
+	flag.BoolVar(&update, "u", false,
+		"Move only when the source file is newer than the target file or "+
+			"when the target file is missing.")
+	flag.BoolVar(&verbose, "v", false,
+		"Cause mv to be verbose, showing files after they are moved.")
+	flag.StringVar(&targetDir, "t", "",
+		"Move all source arguments into the directory DIR.")
+	flag.BoolVar(&noTargetDir, "T", false,
+		"Treat the target as a normal file.")
+	flag.Var(&backupControl, "backup",
+		"Make a backup of each existing target file: none, numbered, "+
+			"existing or simple. If given without a value then existing.")
+	flag.BoolVar(&simpleBackup, "b", false,
+		"Like --backup but does not accept a value.")
+	flag.StringVar(&backupSuffix, "S", backup.DefaultSuffix,
+		"Override the usual backup suffix.")
 }
 
 func main() {
 	flag.Parse()
 
-	from := flag.Arg(0)
-	to := flag.Arg(1)
+	if simpleBackup && backupControl == backup.None {
+		backupControl = backup.Existing
+	}
+
+	fargs := flag.Args()
+
+	var from []string
+	var to string
+
+	if targetDir != "" {
+		from, to = fargs, targetDir
+	} else if len(fargs) > 1 {
+		from, to = fargs[:len(fargs)-1], fargs[len(fargs)-1]
+	}
+
+	// there needs to be at least one source path and a target path, and
+	// with -T exactly one source path
+	if len(from) == 0 || (noTargetDir && (targetDir != "" || len(from) > 1)) {
+		flag.Usage()
+		os.Exit(64)
+	}
+
+	var toIsDir bool
+	if !noTargetDir {
+		if fi, err := os.Stat(to); err == nil {
+			toIsDir = fi.IsDir()
+		}
+	}
 
-	_, err := os.Stat(to)
+	// cannot move more than one source file to a target path if the target
+	// path is not a directory
+	if !toIsDir && (targetDir != "" || len(from) > 1) {
+		fmt.Printf("mv: %s: Not a directory\n", to)
+		os.Exit(64)
+	}
+
+	if swap {
+		if len(from) != 1 {
+			flag.Usage()
+			os.Exit(64)
+		}
+		if err := exchange(from[0], to); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		if verbose {
+			fmt.Printf("%[1]s <-> %[2]s\n", from[0], to)
+		}
+		return
+	}
 
-	if err == nil && doNotOverwrite {
-		os.Exit(0)
+	hasErrs := false
+	for _, p := range from {
+		target := to
+		if toIsDir {
+			target = path.Join(to, path.Base(p))
+		}
+		if err := mv(p, target); err != nil {
+			fmt.Println(err.Error())
+			hasErrs = true
+		}
 	}
 
-	if err := os.Rename(from, to); err != nil {
-		fmt.Println(err.Error())
+	if hasErrs {
 		os.Exit(1)
 	}
 }
+
+func mv(from, to string) error {
+	fromFileInfo, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+
+	// the target path exists. it is replaced according to the overwrite
+	// policy, which does not consider it an error to leave it be. with -n
+	// the existence of the target is checked by the rename itself so that
+	// a target created in the meantime is not replaced
+	noReplace := overwrite == overwriteNever
+	if toFileInfo, err := os.Lstat(to); err == nil {
+		if os.SameFile(fromFileInfo, toFileInfo) {
+			return fmt.Errorf("mv: %s and %s are the same file", from, to)
+		}
+		if noReplace {
+			return nil
+		}
+		if update && !fromFileInfo.ModTime().After(toFileInfo.ModTime()) {
+			return nil
+		}
+		if overwrite == overwritePrompt &&
+			!confirm(fmt.Sprintf("mv: overwrite %s? ", to)) {
+			return nil
+		}
+		if !toFileInfo.IsDir() {
+			name, err := backup.Make(to, backupControl, backupSuffix)
+			if err != nil {
+				return err
+			}
+			if verbose && name != "" {
+				fmt.Printf("%s -> %s (backup)\n", to, name)
+			}
+		}
+	}
+
+	if err := move(from, to, noReplace); err != nil {
+		if noReplace && os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if verbose {
+		fmt.Printf("%[1]s -> %[2]s\n", from, to)
+	}
+	return nil
+}
+
+// confirm writes the prompt to stderr and returns true if the answer read
+// from stdin begins with a y.
+func confirm(prompt string) bool {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, _ := stdin.ReadString('\n')
+	return strings.HasPrefix(strings.ToLower(answer), "y")
+}
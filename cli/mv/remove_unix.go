@@ -0,0 +1,83 @@
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+)
+
+// checkRemovable returns an error if the tree from, described by fi, could
+// not be removed once it had been copied across file systems. Each
+// directory that an entry is removed from must be writable and searchable,
+// sticky directories only allow their owners and the owners of their
+// entries to remove them, and immutable and append-only files cannot be
+// removed at all.
+func checkRemovable(from string, fi os.FileInfo) error {
+	parent := path.Dir(from)
+	pfi, err := os.Stat(parent)
+	if err != nil {
+		return err
+	}
+	return checkRemovableIn(parent, pfi, from, fi)
+}
+
+// checkRemovableIn checks that p, described by fi, and its contents can be
+// removed from the directory dir, described by dfi.
+func checkRemovableIn(
+	dir string, dfi os.FileInfo, p string, fi os.FileInfo) error {
+
+	if err := writableDir(dir); err != nil {
+		return notRemovable(p, err)
+	}
+	if dfi.Mode()&os.ModeSticky != 0 && !ownsEntry(dfi, fi) {
+		return notRemovable(p, unix.EPERM)
+	}
+	if immutable(p, fi) {
+		return notRemovable(p, unix.EPERM)
+	}
+	if !fi.IsDir() {
+		return nil
+	}
+
+	d, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	objs, err := d.Readdir(-1)
+	d.Close()
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		err := checkRemovableIn(p, fi, path.Join(p, o.Name()), o)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownsEntry returns true if the effective user may remove the entry
+// described by fi from the sticky directory described by dfi.
+func ownsEntry(dfi, fi os.FileInfo) bool {
+	euid := os.Geteuid()
+	if euid == 0 {
+		return true
+	}
+	duid, _, ok := attr.Owner(dfi)
+	if !ok || duid == euid {
+		return true
+	}
+	uid, _, ok := attr.Owner(fi)
+	return !ok || uid == euid
+}
+
+func notRemovable(p string, err error) error {
+	return fmt.Errorf("mv: cannot remove %s, so it is not moved: %s", p, err)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// checkRemovable returns an error if the tree from, described by fi, could
+// not be removed once it had been copied across file systems. Windows does
+// not remove read-only files.
+func checkRemovable(from string, fi os.FileInfo) error {
+	if fi.Mode()&os.ModeSymlink == 0 && fi.Mode().Perm()&0200 == 0 {
+		return fmt.Errorf(
+			"mv: cannot remove %s, so it is not moved: file is read-only",
+			from)
+	}
+	if !fi.IsDir() {
+		return nil
+	}
+
+	d, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	objs, err := d.Readdir(-1)
+	d.Close()
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		if err := checkRemovable(path.Join(from, o.Name()), o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameNoReplace renames from to to unless to exists, in which case an
+// error satisfying os.IsExist is returned. The check and the rename are a
+// single atomic operation unless the file system does not support it.
+func renameNoReplace(from, to string) error {
+	err := unix.Renameat2(
+		unix.AT_FDCWD, from, unix.AT_FDCWD, to, unix.RENAME_NOREPLACE)
+	if err == unix.ENOSYS || err == unix.EINVAL {
+		return statAndRename(from, to)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	return nil
+}
+
+// exchange atomically swaps from and to, which must both exist.
+func exchange(from, to string) error {
+	err := unix.Renameat2(
+		unix.AT_FDCWD, from, unix.AT_FDCWD, to, unix.RENAME_EXCHANGE)
+	if err != nil {
+		return &os.LinkError{Op: "exchange", Old: from, New: to, Err: err}
+	}
+	return nil
+}
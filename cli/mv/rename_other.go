@@ -0,0 +1,20 @@
+// +build !linux
+
+package main
+
+import (
+	"errors"
+)
+
+var errExchangeUnsupported = errors.New(
+	"mv: --exchange is not supported on this platform")
+
+// renameNoReplace renames from to to unless to exists, in which case an
+// error satisfying os.IsExist is returned.
+func renameNoReplace(from, to string) error {
+	return statAndRename(from, to)
+}
+
+func exchange(from, to string) error {
+	return errExchangeUnsupported
+}
@@ -1,59 +1,356 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+	"github.com/akutz/gnixutils/lib/os/shred"
+	"github.com/akutz/gnixutils/lib/os/trash"
+)
+
+// promptMode describes when rm asks before removing files.
+type promptMode int
+
+const (
+	// promptNever never prompts.
+	promptNever promptMode = iota
+
+	// promptAlways prompts before every removal (-i).
+	promptAlways
+
+	// promptOnce prompts once before removing more than three files or
+	// removing recursively (-I).
+	promptOnce
+)
+
+// promptFlag implements flag.Value for the -f, -i and -I flags. Whichever
+// of them appears last on the command line decides when rm prompts.
+type promptFlag promptMode
+
+func (f promptFlag) String() string {
+	return "false"
+}
+
+func (f promptFlag) Set(s string) error {
+	if s != "true" {
+		return fmt.Errorf("invalid value %q", s)
+	}
+	prompt = promptMode(f)
+	if prompt == promptNever {
+		force = true
+	}
+	return nil
+}
+
+func (f promptFlag) IsBoolFlag() bool {
+	return true
+}
+
+// shredFlag implements flag.Value for --shred[=N], the number of times
+// files are overwritten before they are removed. Given without a value the
+// files are overwritten shred.DefaultPasses times.
+type shredFlag int
+
+func (f *shredFlag) String() string {
+	if f == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*f))
+}
+
+func (f *shredFlag) Set(s string) error {
+	if s == "true" {
+		*f = shred.DefaultPasses
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid number of passes %q", s)
+	}
+	*f = shredFlag(n)
+	return nil
+}
+
+func (f *shredFlag) IsBoolFlag() bool {
+	return true
+}
+
+// result is the outcome of removing a file.
+type result int
+
+const (
+	removed result = iota
+
+	// kept means the user chose not to remove the file.
+	kept
+
+	failed
 )
 
 var (
-	force bool
-	dirs  bool
+	force          bool
+	prompt         promptMode
+	recursive      bool
+	dirs           bool
+	verbose        bool
+	preserveRoot   bool
+	noPreserveRoot bool
+	oneFileSystem  bool
+	shredPasses    shredFlag
+	shredZero      bool
+	toTrash        bool
+	jobs           int
+
+	// workers limits the number of goroutines removing directory entries
+	// in addition to the main goroutine
+	workers chan struct{}
+
+	stdin = bufio.NewReader(os.Stdin)
 )
 
 func init() {
-	flag.BoolVar(&force, "f", false,
-		"Force removal without prompting for confirmation")
+	flag.Var(promptFlag(promptNever), "f",
+		"Ignore nonexistent files and never prompt")
+	flag.Var(promptFlag(promptAlways), "i",
+		"Prompt before every removal")
+	flag.Var(promptFlag(promptOnce), "I",
+		"Prompt once before removing more than three files or removing "+
+			"recursively")
+	flag.BoolVar(&recursive, "r", false,
+		"Remove directories and their contents recursively")
+	flag.BoolVar(&recursive, "R", false,
+		"Remove directories and their contents recursively")
 	flag.BoolVar(&dirs, "d", false,
-		"Attempt to remove directories")
+		"Remove empty directories")
+	flag.BoolVar(&verbose, "v", false,
+		"Show files as they are removed")
+	flag.BoolVar(&preserveRoot, "preserve-root", true,
+		"Do not remove / recursively")
+	flag.BoolVar(&noPreserveRoot, "no-preserve-root", false,
+		"Do not treat / specially")
+	flag.BoolVar(&oneFileSystem, "one-file-system", false,
+		"When removing recursively, skip directories on file systems "+
+			"other than that of the corresponding argument")
+	flag.Var(&shredPasses, "shred",
+		"Overwrite regular files N times, 3 if N is omitted, and hide "+
+			"their names before removing them")
+	flag.BoolVar(&shredZero, "shred-zero", false,
+		"With --shred, add a final overwrite with zeros to hide shredding")
+	flag.BoolVar(&toTrash, "trash", false,
+		"Move files to the trash instead of removing them")
+	flag.IntVar(&jobs, "jobs", 1,
+		"Remove the contents of directories using up to N concurrent jobs")
 }
 
 func main() {
 	flag.Parse()
-	for _, p := range flag.Args() {
-		rm(p)
+
+	if noPreserveRoot {
+		preserveRoot = false
+	}
+
+	if toTrash && shredPasses > 0 {
+		fmt.Println("rm: --shred and --trash may not be used together")
+		os.Exit(1)
+	}
+
+	if jobs > 1 {
+		workers = make(chan struct{}, jobs-1)
+	}
+
+	args := flag.Args()
+	if prompt == promptOnce && (recursive || len(args) > 3) {
+		q := "rm: remove %d arguments? "
+		if recursive {
+			q = "rm: remove %d arguments recursively? "
+		}
+		if !confirm(fmt.Sprintf(q, len(args))) {
+			return
+		}
+	}
+
+	hasErrs := false
+	for _, p := range args {
+		if rm(p) == failed {
+			hasErrs = true
+		}
+	}
+
+	if hasErrs {
+		os.Exit(1)
 	}
 }
 
-func rm(p string) {
-	file, err := os.Stat(p)
+// rm removes the path p given on the command line.
+func rm(p string) result {
+	file, err := os.Lstat(p)
 
 	if err != nil && os.IsNotExist(err) {
 		if force {
-			os.Exit(0)
-		} else {
-			fmt.Printf("rm: %s: No such file or directory\n", p)
-			os.Exit(1)
+			return removed
 		}
+		fmt.Printf("rm: %s: No such file or directory\n", p)
+		return failed
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		return failed
 	}
 
-	if file.IsDir() && !dirs {
-		if force {
-			os.Exit(0)
-		} else {
-			fmt.Printf("rm: %s: is a directory\n", p)
-			os.Exit(1)
+	if base := path.Base(p); base == "." || base == ".." {
+		fmt.Printf("rm: refusing to remove '.' or '..' directory: %s\n", p)
+		return failed
+	}
+
+	if !file.IsDir() {
+		return remove(p, file)
+	}
+
+	if recursive {
+		if preserveRoot && isRoot(file) {
+			fmt.Printf(
+				"rm: it is dangerous to operate recursively on %s\n", p)
+			fmt.Println(
+				"rm: use --no-preserve-root to override this failsafe")
+			return failed
+		}
+		if toTrash {
+			return remove(p, file)
 		}
+		id, _, _ := attr.ID(file)
+		if prompt == promptAlways {
+			return removeTree(p, file, id.Dev)
+		}
+		return removeTreeAt(p, file, id.Dev)
 	}
 
 	if dirs {
-		err = os.RemoveAll(p)
-	} else {
-		err = os.Remove(p)
+		if toTrash && !isEmpty(p) {
+			fmt.Printf("rm: %s: Directory not empty\n", p)
+			return failed
+		}
+		return remove(p, file)
 	}
 
-	if !force && err != nil {
+	fmt.Printf("rm: %s: is a directory\n", p)
+	return failed
+}
+
+// removeTree removes the directory p, described by fi, and its contents
+// one file at a time, prompting with -i. With --one-file-system directories
+// on a device other than dev are skipped.
+func removeTree(p string, fi os.FileInfo, dev uint64) result {
+	if prompt == promptAlways &&
+		!confirm(fmt.Sprintf("rm: descend into directory %s? ", p)) {
+		return kept
+	}
+
+	d, err := os.Open(p)
+	if err != nil {
 		fmt.Println(err.Error())
-		os.Exit(1)
+		return failed
+	}
+	objs, err := d.Readdir(-1)
+	d.Close()
+	if err != nil {
+		fmt.Println(err.Error())
+		return failed
+	}
+
+	res := removed
+	for _, o := range objs {
+		op := path.Join(p, o.Name())
+
+		var r result
+		switch {
+		case !o.IsDir():
+			r = remove(op, o)
+		case oneFileSystem && !sameDevice(o, dev):
+			fmt.Printf(
+				"rm: skipping %s, since it's on a different device\n", op)
+			r = failed
+		default:
+			r = removeTree(op, o, dev)
+		}
+
+		if r > res {
+			res = r
+		}
+	}
+
+	// a directory that still has contents cannot be removed
+	if res != removed {
+		return res
+	}
+	return remove(p, fi)
+}
+
+// remove removes the file or empty directory p, described by fi, prompting
+// first with -i. With --shred regular files are overwritten first, and
+// with --trash p is moved to the trash instead.
+func remove(p string, fi os.FileInfo) result {
+	if prompt == promptAlways &&
+		!confirm(fmt.Sprintf("rm: remove %s? ", p)) {
+		return kept
+	}
+
+	var err error
+	switch {
+	case toTrash:
+		_, err = trash.Put(p)
+	case shredPasses > 0 && fi.Mode().IsRegular():
+		err = shred.File(p, shred.Options{
+			Passes: int(shredPasses),
+			Zero:   shredZero,
+			Remove: true,
+			Force:  true,
+		})
+	default:
+		err = os.Remove(p)
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		return failed
 	}
+
+	if verbose {
+		fmt.Println(p)
+	}
+	return removed
+}
+
+func sameDevice(fi os.FileInfo, dev uint64) bool {
+	id, _, ok := attr.ID(fi)
+	return !ok || id.Dev == dev
+}
+
+// isEmpty returns true if p is an empty directory.
+func isEmpty(p string) bool {
+	d, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer d.Close()
+	names, _ := d.Readdirnames(1)
+	return len(names) == 0
+}
+
+// isRoot returns true if fi describes the root directory.
+func isRoot(fi os.FileInfo) bool {
+	root, err := os.Lstat("/")
+	return err == nil && os.SameFile(fi, root)
+}
+
+// confirm writes the prompt to stderr and returns true if the answer read
+// from stdin begins with a y.
+func confirm(prompt string) bool {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, _ := stdin.ReadString('\n')
+	return strings.HasPrefix(strings.ToLower(answer), "y")
 }
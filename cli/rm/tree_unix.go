@@ -0,0 +1,272 @@
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// batchSize is the number of files in a directory that one job unlinks
+// before the rest are handed to another job.
+const batchSize = 1024
+
+// openDirs limits the directories that removeDirAt holds open to half the
+// limit on open files.
+var openDirs chan struct{}
+
+func init() {
+	n := uint64(1 << 16)
+	var rl unix.Rlimit
+	if unix.Getrlimit(unix.RLIMIT_NOFILE, &rl) == nil &&
+		uint64(rl.Cur)/2 < n {
+
+		n = uint64(rl.Cur) / 2
+	}
+	openDirs = make(chan struct{}, n)
+}
+
+// removeTreeAt removes the directory p, described by fi, and its contents.
+// Each directory is opened once and its entries are removed with unlinkat
+// relative to it, so no path is resolved more than one level deep. A
+// directory stays open while its subdirectories are removed, so once half
+// the limit on open files is reached the rest of a deeper tree is removed
+// by path with removeTree instead. The subdirectories and batches of files
+// in a directory are removed by up to --jobs concurrent jobs. An error is
+// reported for each path that cannot be removed and removal continues with
+// the rest of the tree.
+func removeTreeAt(p string, fi os.FileInfo, dev uint64) result {
+	p = path.Clean(p)
+	parent, err := unix.Open(path.Dir(p),
+		unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		fmt.Println((&os.PathError{Op: "open", Path: p, Err: err}).Error())
+		return failed
+	}
+	defer unix.Close(parent)
+
+	var st unix.Stat_t
+	if err := unix.Fstatat(
+		parent, path.Base(p), &st, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		fmt.Println((&os.PathError{Op: "stat", Path: p, Err: err}).Error())
+		return failed
+	}
+	return removeDirAt(parent, nil, path.Base(p), p, &st, dev)
+}
+
+// removeDirAt removes the directory name, described by st, in the
+// directory parent. If pw is not nil it may be used to make parent
+// writable. The directory's path p is only used for messages and for the
+// files removed with --shred.
+func removeDirAt(
+	parent int,
+	pw *writable,
+	name, p string,
+	st *unix.Stat_t,
+	dev uint64) result {
+
+	select {
+	case openDirs <- struct{}{}:
+		defer func() { <-openDirs }()
+	default:
+		return removeTreeByPath(p, dev)
+	}
+
+	// the mode is restored if the directory is not removed after all
+	perm := uint32(st.Mode) & 07777
+	chmodded := false
+
+	fd, err := openDir(parent, name)
+	if err == unix.EACCES && force {
+		// the owner of a directory without read or search permission may
+		// still grant it to themselves
+		err = unix.Fchmodat(parent, name, perm|0700, 0)
+		if err == nil {
+			chmodded = true
+			fd, err = openDir(parent, name)
+		}
+	}
+	if err != nil {
+		if chmodded {
+			unix.Fchmodat(parent, name, perm, 0)
+		}
+		fmt.Println((&os.PathError{Op: "open", Path: p, Err: err}).Error())
+		return failed
+	}
+	d := os.NewFile(uintptr(fd), p)
+	defer d.Close()
+
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		fmt.Println(err.Error())
+		return failed
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		res = removed
+	)
+	merge := func(r result) {
+		mu.Lock()
+		if r > res {
+			res = r
+		}
+		mu.Unlock()
+	}
+	spawn := func(f func() result) {
+		select {
+		case workers <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer func() { <-workers; wg.Done() }()
+				merge(f())
+			}()
+		default:
+			merge(f())
+		}
+	}
+
+	w := &writable{fd: fd}
+	var files []string
+	for _, n := range names {
+		op := path.Join(p, n)
+
+		var est unix.Stat_t
+		err := unix.Fstatat(fd, n, &est, unix.AT_SYMLINK_NOFOLLOW)
+		if err == unix.ENOENT {
+			continue
+		}
+		if err != nil {
+			fmt.Println(
+				(&os.PathError{Op: "stat", Path: op, Err: err}).Error())
+			merge(failed)
+			continue
+		}
+
+		if est.Mode&unix.S_IFMT != unix.S_IFDIR {
+			files = append(files, n)
+			if len(files) == batchSize {
+				batch := files
+				spawn(func() result { return unlinkFiles(w, p, batch) })
+				files = nil
+			}
+			continue
+		}
+
+		if oneFileSystem && uint64(est.Dev) != dev {
+			fmt.Printf(
+				"rm: skipping %s, since it's on a different device\n", op)
+			merge(failed)
+			continue
+		}
+
+		n := n
+		spawn(func() result { return removeDirAt(fd, w, n, op, &est, dev) })
+	}
+	if len(files) > 0 {
+		merge(unlinkFiles(w, p, files))
+	}
+	wg.Wait()
+
+	// a directory that still has contents cannot be removed
+	if res == removed {
+		res = unlinkAt(parent, name, p, unix.AT_REMOVEDIR, pw)
+	}
+	if res != removed && (chmodded || w.ok) {
+		unix.Fchmod(fd, perm)
+	}
+	return res
+}
+
+// removeTreeByPath removes the directory p and its contents with
+// removeTree.
+func removeTreeByPath(p string, dev uint64) result {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		fmt.Println(err.Error())
+		return failed
+	}
+	return removeTree(p, fi, dev)
+}
+
+// unlinkFiles removes the files names in the directory p, which is open
+// as w.fd.
+func unlinkFiles(w *writable, p string, names []string) result {
+	res := removed
+	for _, n := range names {
+		op := path.Join(p, n)
+
+		var r result
+		if shredPasses > 0 {
+			r = shredAt(op)
+		} else {
+			r = unlinkAt(w.fd, n, op, 0, w)
+		}
+		if r > res {
+			res = r
+		}
+	}
+	return res
+}
+
+// shredAt shreds and removes the file p if it is a regular file.
+func shredAt(p string) result {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		fmt.Println(err.Error())
+		return failed
+	}
+	return remove(p, fi)
+}
+
+// unlinkAt removes name from the directory dirfd. If w is not nil and the
+// directory is not writable then, with -f, write permission is added to it
+// and the removal is tried again.
+func unlinkAt(dirfd int, name, p string, flags int, w *writable) result {
+	err := unix.Unlinkat(dirfd, name, flags)
+	if err == unix.EACCES && w != nil && force && w.grant() {
+		err = unix.Unlinkat(dirfd, name, flags)
+	}
+	if err != nil && err != unix.ENOENT {
+		fmt.Println(
+			(&os.PathError{Op: "unlinkat", Path: p, Err: err}).Error())
+		return failed
+	}
+
+	if verbose {
+		fmt.Println(p)
+	}
+	return removed
+}
+
+// writable adds write and search permission for the owner to the
+// directory fd, at most once, for the jobs removing its entries. ok is
+// true if the permission was added.
+type writable struct {
+	fd   int
+	once sync.Once
+	ok   bool
+}
+
+func (w *writable) grant() bool {
+	w.once.Do(func() {
+		var st unix.Stat_t
+		if unix.Fstat(w.fd, &st) != nil || st.Mode&0300 == 0300 {
+			return
+		}
+		w.ok = unix.Fchmod(w.fd, uint32(st.Mode)&07777|0300) == nil
+	})
+	return w.ok
+}
+
+// openDir opens the directory name in the directory dirfd without
+// following a symbolic link.
+func openDir(dirfd int, name string) (int, error) {
+	return unix.Openat(dirfd, name,
+		unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+}
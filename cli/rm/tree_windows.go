@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+// removeTreeAt removes the directory p, described by fi, and its contents.
+// Windows has no unlinkat, so the tree is removed one path at a time.
+func removeTreeAt(p string, fi os.FileInfo, dev uint64) result {
+	return removeTree(p, fi, dev)
+}
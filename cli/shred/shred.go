@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/akutz/gnixutils/lib/os/shred"
+)
+
+var (
+	passes  int
+	zero    bool
+	remove  bool
+	force   bool
+	verbose bool
+)
+
+func init() {
+	flag.IntVar(&passes, "n", shred.DefaultPasses,
+		"Overwrite the files this many times")
+	flag.BoolVar(&zero, "z", false,
+		"Add a final overwrite with zeros to hide shredding")
+	flag.BoolVar(&remove, "u", false,
+		"Truncate, rename and remove the files after overwriting them")
+	flag.BoolVar(&force, "f", false,
+		"Change permissions to allow writing if necessary")
+	flag.BoolVar(&verbose, "v", false,
+		"Show progress")
+}
+
+func main() {
+	flag.Parse()
+
+	if passes < 0 {
+		fmt.Printf("shred: invalid number of passes: %d\n", passes)
+		os.Exit(1)
+	}
+
+	o := shred.Options{
+		Passes: passes,
+		Zero:   zero,
+		Remove: remove,
+		Force:  force,
+	}
+	if verbose {
+		o.Report = func(msg string) {
+			fmt.Fprintf(os.Stderr, "shred: %s\n", msg)
+		}
+	}
+
+	hasErrs := false
+	for _, p := range flag.Args() {
+		if err := shred.File(p, o); err != nil {
+			fmt.Println(err.Error())
+			hasErrs = true
+		}
+	}
+
+	if hasErrs {
+		os.Exit(1)
+	}
+}
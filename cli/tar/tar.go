@@ -8,10 +8,10 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/user"
 	"path"
 
 	"github.com/akutz/gnixutils/lib/os/group"
+	"github.com/akutz/gnixutils/lib/os/user"
 )
 
 var (
@@ -151,12 +151,12 @@ func addToTar(p string, w *tar.Writer) {
 	}
 	h.Name = p
 
-	user, err := user.LookupId(fmt.Sprintf("%d", h.Uid))
+	usr, err := user.LookupUserID(fmt.Sprintf("%d", h.Uid))
 	if err != nil {
 		fmt.Printf("tar: %s: error getting file's owner: %v\n", p, err)
 		os.Exit(1)
 	}
-	h.Uname = user.Username
+	h.Uname = usr.Username
 
 	grp, err := group.LookupGroupID(fmt.Sprintf("%d", h.Gid))
 	if err != nil {
@@ -3,41 +3,161 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"syscall"
 	"time"
+
+	"github.com/akutz/gnixutils/lib/date"
+	"github.com/akutz/gnixutils/lib/os/attr"
 )
 
 var (
-	emptyBuf    = []byte{}
 	doNotCreate bool
-	now         time.Time
+	accessOnly  bool
+	modifyOnly  bool
+	noDeref     bool
+	reference   string
+	stamp       string
+	dateStr     string
+
+	// atime and mtime are the times the files are given.
+	atime attr.Time
+	mtime attr.Time
 )
 
 func init() {
-	now = time.Now()
 	flag.BoolVar(&doNotCreate, "c", false,
 		"Do not create the file if it does not exist.")
+	flag.BoolVar(&accessOnly, "a", false,
+		"Change only the access time.")
+	flag.BoolVar(&modifyOnly, "m", false,
+		"Change only the modification time.")
+	flag.BoolVar(&noDeref, "h", false,
+		"Change the times of symbolic links instead of the files they "+
+			"refer to. Files are not created.")
+	flag.StringVar(&reference, "r", "",
+		"Use the times of this file instead of the current time.")
+	flag.StringVar(&stamp, "t", "",
+		"Use [[CC]YY]MMDDhhmm[.ss] instead of the current time.")
+	flag.StringVar(&dateStr, "d", "",
+		"Parse a date string, such as \"2006-01-02 15:04\" or "+
+			"\"2 days ago\", and use it instead of the current time.")
 }
 
 func main() {
 	flag.Parse()
+
+	if stamp != "" && (dateStr != "" || reference != "") {
+		fmt.Println("touch: cannot specify times from more than one source")
+		os.Exit(1)
+	}
+	if err := initTimes(); err != nil {
+		fmt.Printf("touch: %s\n", err)
+		os.Exit(1)
+	}
+
+	hasErrs := false
 	for _, p := range flag.Args() {
-		touch(p)
+		if err := touch(p); err != nil {
+			fmt.Println(err.Error())
+			hasErrs = true
+		}
+	}
+
+	if hasErrs {
+		os.Exit(1)
 	}
 }
 
-func touch(p string) {
-	_, err := os.Stat(p)
+// initTimes sets atime and mtime from -a, -m, -r, -t and -d. Without a
+// source of times the files are given the current time, which only needs
+// write access to them. With both -r and -d the date is relative to the
+// times of the reference file. The time that -a or -m does not change is
+// omitted, so it is never read and written back.
+func initTimes() error {
+	atime, mtime = attr.Now, attr.Now
 
-	if err != nil && os.IsNotExist(err) {
-		if doNotCreate {
-			os.Exit(0)
-		} else if err := ioutil.WriteFile(p, emptyBuf, 0644); err != nil {
-			fmt.Println(err.Error())
-			os.Exit(1)
+	if reference != "" || dateStr != "" || stamp != "" {
+		a, m, err := sourceTimes()
+		if err != nil {
+			return err
 		}
-	} else {
-		os.Chtimes(p, now, now)
+		atime, mtime = attr.At(a), attr.At(m)
+	}
+
+	if modifyOnly && !accessOnly {
+		atime = attr.Omit
+	}
+	if accessOnly && !modifyOnly {
+		mtime = attr.Omit
+	}
+	return nil
+}
+
+// sourceTimes returns the access and modification times given by -r, -t
+// and -d.
+func sourceTimes() (time.Time, time.Time, error) {
+	now := time.Now()
+	if stamp != "" {
+		t, err := date.ParseStamp(stamp, now)
+		return t, t, err
+	}
+
+	a, m := now, now
+	if reference != "" {
+		fi, err := stat(reference, !noDeref)
+		if err != nil {
+			return a, m, err
+		}
+		a, m = attr.Atime(fi), fi.ModTime()
+	}
+
+	if dateStr != "" {
+		var err error
+		if a, err = date.Parse(dateStr, a); err != nil {
+			return a, m, err
+		}
+		if m, err = date.Parse(dateStr, m); err != nil {
+			return a, m, err
+		}
+	}
+	return a, m, nil
+}
+
+// touch creates the file p if it does not exist and sets its times. Like
+// GNU touch, files are not created with -h.
+func touch(p string) error {
+	var openErr error
+	if !doNotCreate && !noDeref {
+		openErr = create(p)
+	}
+
+	err := attr.Chtimes(p, atime, mtime, !noDeref)
+	if err == nil {
+		return nil
+	}
+	if doNotCreate && os.IsNotExist(err) {
+		return nil
+	}
+	if openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// create creates the empty file p if it does not exist. The file is opened
+// without blocking, so that a FIFO does not hang.
+func create(p string) error {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|syscall.O_NONBLOCK, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func stat(p string, deref bool) (os.FileInfo, error) {
+	if deref {
+		return os.Stat(p)
 	}
+	return os.Lstat(p)
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/akutz/gnixutils/lib/os/trash"
+)
+
+var (
+	list    bool
+	restore bool
+	verbose bool
+)
+
+func init() {
+	flag.BoolVar(&list, "l", false,
+		"List the files in the trash.")
+	flag.BoolVar(&restore, "r", false,
+		"Restore the specified files, given by their original paths.")
+	flag.BoolVar(&verbose, "v", false,
+		"Produce verbose output.")
+}
+
+// byDeleted sorts items by the time they were moved to the trash.
+type byDeleted []*trash.Item
+
+func (s byDeleted) Len() int {
+	return len(s)
+}
+
+func (s byDeleted) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s byDeleted) Less(i, j int) bool {
+	return s[i].Deleted.Before(s[j].Deleted)
+}
+
+func main() {
+	flag.Parse()
+
+	var ok bool
+	switch {
+	case list:
+		ok = listTrash()
+	case restore:
+		ok = restoreFiles(flag.Args())
+	default:
+		ok = putFiles(flag.Args())
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func putFiles(paths []string) bool {
+	ok := true
+	for _, p := range paths {
+		item, err := trash.Put(p)
+		if err != nil {
+			fmt.Println(err.Error())
+			ok = false
+			continue
+		}
+		if verbose {
+			fmt.Printf("%s -> %s\n", p, item.File())
+		}
+	}
+	return ok
+}
+
+// items returns the items in all of the user's trash directories, oldest
+// first.
+func items() ([]*trash.Item, error) {
+	cans, err := trash.Cans()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*trash.Item
+	for _, c := range cans {
+		items, err := trash.List(c)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+	}
+	sort.Stable(byDeleted(all))
+	return all, nil
+}
+
+func listTrash() bool {
+	all, err := items()
+	if err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	for _, i := range all {
+		fmt.Printf("%s %s\n",
+			i.Deleted.Format("2006-01-02 15:04:05"), i.Path)
+		if verbose {
+			fmt.Printf("    %s\n", i.File())
+		}
+	}
+	return true
+}
+
+// restoreFiles restores the files deleted from paths. If a path was
+// deleted more than once then the most recently deleted file is restored.
+func restoreFiles(paths []string) bool {
+	all, err := items()
+	if err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+
+	ok := true
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			fmt.Println(err.Error())
+			ok = false
+			continue
+		}
+
+		var item *trash.Item
+		for _, i := range all {
+			if i.Path == abs {
+				item = i
+			}
+		}
+		if item == nil {
+			fmt.Printf("trash: %s: not in the trash\n", p)
+			ok = false
+			continue
+		}
+
+		if err := trash.Restore(item); err != nil {
+			fmt.Println(err.Error())
+			ok = false
+			continue
+		}
+		if verbose {
+			fmt.Printf("%s -> %s\n", item.File(), item.Path)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/akutz/gnixutils/lib/os/user"
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() > 0 {
+		fmt.Printf("whoami: extra operand '%s'\n", flag.Arg(0))
+		os.Exit(1)
+	}
+
+	uid := os.Geteuid()
+	usr, err := user.LookupUserID(strconv.Itoa(uid))
+
+	// there are no numeric user IDs to look up where the user database is
+	// unsupported, but the current user is still known
+	if err == nil && usr.Username == "" {
+		usr, err = user.Current()
+	}
+	if err != nil || usr.Username == "" {
+		fmt.Printf("whoami: cannot find name for user ID %d\n", uid)
+		os.Exit(1)
+	}
+
+	fmt.Println(usr.Username)
+}
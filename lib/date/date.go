@@ -0,0 +1,243 @@
+// Package date parses the dates and times given to commands such as touch.
+package date
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// stampRx matches the stamp format, [[CC]YY]MMDDhhmm[.ss].
+	stampRx = regexp.MustCompile(`^(\d{2}){4,6}(\.\d{2})?$`)
+
+	// dateLayouts are the absolute dates and times that Parse accepts.
+	dateLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05 -07:00",
+		"2006-01-02 15:04:05 MST",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2006/01/02 15:04:05",
+		"2006/01/02 15:04",
+		"2006/01/02",
+		"01/02/2006 15:04:05",
+		"01/02/2006 15:04",
+		"01/02/2006",
+		"Jan 2 2006 15:04:05",
+		"Jan 2 2006",
+		"Jan 2, 2006",
+		"2 Jan 2006 15:04:05",
+		"2 Jan 2006",
+		"January 2 2006",
+		"January 2, 2006",
+		"2 January 2006",
+		time.UnixDate,
+		time.ANSIC,
+		time.RubyDate,
+		time.RFC1123,
+		time.RFC1123Z,
+		time.RFC850,
+		time.RFC822,
+		time.RFC822Z,
+	}
+
+	// timeLayouts are the times of day that Parse accepts. The date is that of
+	// the base time.
+	timeLayouts = []string{
+		"15:04:05",
+		"15:04",
+		"3:04:05PM",
+		"3:04PM",
+		"3PM",
+	}
+
+	// relUnits are the units of relative items such as "2 days".
+	relUnits = map[string]func(t time.Time, n int) time.Time{
+		"year": func(t time.Time, n int) time.Time {
+			return t.AddDate(n, 0, 0)
+		},
+		"month": func(t time.Time, n int) time.Time {
+			return t.AddDate(0, n, 0)
+		},
+		"fortnight": func(t time.Time, n int) time.Time {
+			return t.AddDate(0, 0, 14*n)
+		},
+		"week": func(t time.Time, n int) time.Time {
+			return t.AddDate(0, 0, 7*n)
+		},
+		"day": func(t time.Time, n int) time.Time {
+			return t.AddDate(0, 0, n)
+		},
+		"hour":   addDuration(time.Hour),
+		"minute": addDuration(time.Minute),
+		"min":    addDuration(time.Minute),
+		"second": addDuration(time.Second),
+		"sec":    addDuration(time.Second),
+	}
+
+	// relWords are the words that stand for relative items on their own.
+	relWords = map[string]int{
+		"now":       0,
+		"today":     0,
+		"yesterday": -1,
+		"tomorrow":  1,
+	}
+)
+
+func addDuration(d time.Duration) func(t time.Time, n int) time.Time {
+	return func(t time.Time, n int) time.Time {
+		return t.Add(time.Duration(n) * d)
+	}
+}
+
+// ParseStamp parses the stamp format of touch -t, [[CC]YY]MMDDhhmm[.ss], in
+// the location of now. Without a century, years 69 to 99 are in the 1900s
+// and the rest in the 2000s. Without a year, the year of now is used.
+func ParseStamp(s string, now time.Time) (time.Time, error) {
+	if !stampRx.MatchString(s) {
+		return time.Time{}, fmt.Errorf("invalid date format '%s'", s)
+	}
+
+	digits, sec := s, 0
+	if i := strings.Index(s, "."); i >= 0 {
+		digits = s[:i]
+		sec, _ = strconv.Atoi(s[i+1:])
+	}
+
+	var n []int
+	for i := 0; i < len(digits); i += 2 {
+		d, _ := strconv.Atoi(digits[i : i+2])
+		n = append(n, d)
+	}
+
+	year := now.Year()
+	switch len(n) {
+	case 6:
+		year, n = n[0]*100+n[1], n[2:]
+	case 5:
+		year, n = 2000+n[0], n[1:]
+		if year >= 2069 {
+			year -= 100
+		}
+	}
+
+	// time.Date normalizes out of range values, such as February 30
+	t := time.Date(year, time.Month(n[0]), n[1], n[2], n[3], sec, 0,
+		now.Location())
+	if t.Year() != year || int(t.Month()) != n[0] || t.Day() != n[1] ||
+		t.Hour() != n[2] || t.Minute() != n[3] || t.Second() != sec {
+
+		return time.Time{}, fmt.Errorf("invalid date format '%s'", s)
+	}
+	return t, nil
+}
+
+// Parse parses the free-form date strings of touch -d. A date is an
+// absolute date and time, or a time of day, and relative items such as
+// "+2 days", "3 hours ago", "next week" or "yesterday". The relative items
+// are applied to the absolute date, or to base if there is none. Seconds
+// since the epoch may be given as @SECONDS. Dates without a time zone are
+// in the location of base.
+func Parse(s string, base time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return base, nil
+	}
+
+	if strings.HasPrefix(s, "@") {
+		f, err := strconv.ParseFloat(s[1:], 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date '%s'", s)
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).In(
+			base.Location()), nil
+	}
+
+	// find the longest run of fields that is an absolute date, with
+	// relative items around it, such as "yesterday 15:00"
+	fields := strings.Fields(s)
+	for n := len(fields); n >= 0; n-- {
+		for i := 0; i+n <= len(fields); i++ {
+			t, ok := parseAbsolute(strings.Join(fields[i:i+n], " "), base)
+			if !ok {
+				continue
+			}
+			rel := append(append([]string{}, fields[:i]...), fields[i+n:]...)
+			if t, ok = parseRelative(rel, t); ok {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s'", s)
+}
+
+// parseAbsolute parses an absolute date and time, or a time of day on the
+// date of base. An empty string is base itself.
+func parseAbsolute(s string, base time.Time) (time.Time, bool) {
+	if s == "" {
+		return base, true
+	}
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l, s, base.Location()); err == nil {
+			return t, true
+		}
+	}
+	s = strings.ToUpper(s)
+	for _, l := range timeLayouts {
+		if t, err := time.ParseInLocation(l, s, base.Location()); err == nil {
+			return time.Date(base.Year(), base.Month(), base.Day(),
+				t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
+				base.Location()), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseRelative applies the relative items in fields to t.
+func parseRelative(fields []string, t time.Time) (time.Time, bool) {
+	for i := 0; i < len(fields); i++ {
+		f := strings.ToLower(fields[i])
+		if d, ok := relWords[f]; ok {
+			t = t.AddDate(0, 0, d)
+			continue
+		}
+
+		// an item is an optional number, or next or last, and a unit
+		n := 1
+		switch f {
+		case "next":
+			i++
+		case "last":
+			n = -1
+			i++
+		default:
+			if v, err := strconv.Atoi(f); err == nil {
+				n = v
+				i++
+			}
+		}
+		if i >= len(fields) {
+			return time.Time{}, false
+		}
+
+		unit := strings.TrimSuffix(strings.ToLower(fields[i]), "s")
+		add, ok := relUnits[unit]
+		if !ok {
+			return time.Time{}, false
+		}
+		if i+1 < len(fields) && strings.ToLower(fields[i+1]) == "ago" {
+			n = -n
+			i++
+		}
+		t = add(t, n)
+	}
+	return t, true
+}
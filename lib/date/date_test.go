@@ -0,0 +1,92 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	utc1 = time.FixedZone("UTC+1", 3600)
+	now  = time.Date(2020, 6, 15, 12, 30, 45, 0, utc1)
+)
+
+func TestParseStamp(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want time.Time
+	}{
+		{"06151230", time.Date(2020, 6, 15, 12, 30, 0, 0, utc1)},
+		{"06151230.59", time.Date(2020, 6, 15, 12, 30, 59, 0, utc1)},
+		{"6801020304", time.Date(2068, 1, 2, 3, 4, 0, 0, utc1)},
+		{"6901020304", time.Date(1969, 1, 2, 3, 4, 0, 0, utc1)},
+		{"9912312359", time.Date(1999, 12, 31, 23, 59, 0, 0, utc1)},
+		{"190001020304", time.Date(1900, 1, 2, 3, 4, 0, 0, utc1)},
+		{"202402291200.30", time.Date(2024, 2, 29, 12, 0, 30, 0, utc1)},
+	} {
+		got, err := ParseStamp(tt.s, now)
+		if assert.NoError(t, err, tt.s) {
+			assert.True(t, tt.want.Equal(got), "%s: %s", tt.s, got)
+		}
+	}
+}
+
+func TestParseStampInvalid(t *testing.T) {
+	for _, s := range []string{
+		"", "0615", "061512301", "0615123", "06151230.5", "06151230.",
+		"a6151230", "13011200", "02301200", "06152400", "06151260",
+		"0615123060", "202302291200", "06151230.60", " 06151230",
+	} {
+		_, err := ParseStamp(s, now)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want time.Time
+	}{
+		{"", now},
+		{"now", now},
+		{"today", now},
+		{"yesterday", now.AddDate(0, 0, -1)},
+		{"tomorrow", now.AddDate(0, 0, 1)},
+		{"2 days ago", now.AddDate(0, 0, -2)},
+		{"+3 hours", now.Add(3 * time.Hour)},
+		{"-1 week", now.AddDate(0, 0, -7)},
+		{"next month", now.AddDate(0, 1, 0)},
+		{"last year", now.AddDate(-1, 0, 0)},
+		{"1 fortnight 10 mins", now.AddDate(0, 0, 14).Add(10 * time.Minute)},
+		{"15:00", time.Date(2020, 6, 15, 15, 0, 0, 0, utc1)},
+		{"3pm", time.Date(2020, 6, 15, 15, 0, 0, 0, utc1)},
+		{"yesterday 15:00", time.Date(2020, 6, 14, 15, 0, 0, 0, utc1)},
+		{"2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, utc1)},
+		{"2006-01-02 15:04:05",
+			time.Date(2006, 1, 2, 15, 4, 5, 0, utc1)},
+		{"2006-01-02 15:04:05 -0700",
+			time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05Z",
+			time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Jan 2, 2006", time.Date(2006, 1, 2, 0, 0, 0, 0, utc1)},
+		{"2006-01-02 1 day ago", time.Date(2006, 1, 1, 0, 0, 0, 0, utc1)},
+		{"@0", time.Unix(0, 0)},
+		{"@1.5", time.Unix(1, 5e8)},
+	} {
+		got, err := Parse(tt.s, now)
+		if assert.NoError(t, err, tt.s) {
+			assert.True(t, tt.want.Equal(got), "%s: %s", tt.s, got)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"x", "@", "@x", "2 parsecs", "next", "3", "ago",
+		"2006-13-02", "25:00", "yesterday x",
+	} {
+		_, err := Parse(s, now)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,12 @@
+// +build aix dragonfly linux openbsd solaris
+
+package attr
+
+import (
+	"syscall"
+	"time"
+)
+
+func statAtime(st *syscall.Stat_t) time.Time {
+	return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+}
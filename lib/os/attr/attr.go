@@ -0,0 +1,46 @@
+/*
+Package attr reads the attributes of files and copies them from one file to
+another. It provides the ownership, timestamps and extended attributes that
+commands such as cp and mv preserve when copying files.
+*/
+package attr
+
+import (
+	"time"
+)
+
+// FileID identifies a file by its device and inode numbers.
+type FileID struct {
+	Dev uint64
+	Ino uint64
+}
+
+// Time is a time given to Chtimes. It is either a point in time, made with
+// At, or one of Omit and Now.
+type Time struct {
+	t    time.Time
+	kind timeKind
+}
+
+type timeKind int
+
+const (
+	timeAt timeKind = iota
+	timeOmit
+	timeNow
+)
+
+var (
+	// Omit leaves a time unchanged.
+	Omit = Time{kind: timeOmit}
+
+	// Now sets a time to the current time. Setting a file's times to the
+	// current time only requires write access to the file, while setting
+	// them to any other time requires owning it.
+	Now = Time{kind: timeNow}
+)
+
+// At returns the Time t.
+func At(t time.Time) Time {
+	return Time{t: t}
+}
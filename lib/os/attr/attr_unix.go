@@ -0,0 +1,90 @@
+// +build !windows
+
+package attr
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ID returns the ID of the file described by fi as well as the file's
+// number of hard links. The returned bool is false if the file system
+// does not provide them.
+func ID(fi os.FileInfo) (FileID, uint64, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, 0, false
+	}
+	return FileID{uint64(st.Dev), uint64(st.Ino)}, uint64(st.Nlink), true
+}
+
+// Owner returns the user and group IDs of the owner of the file described
+// by fi. The returned bool is false if the file system does not provide
+// them.
+func Owner(fi os.FileInfo) (int, int, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
+
+// Lchown changes the owner and group of p to those described by fi without
+// following p if it is a symbolic link. Only the superuser may give a file
+// away, so a permission error is not treated as a failure.
+func Lchown(p string, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	err := os.Lchown(p, int(st.Uid), int(st.Gid))
+	if err != nil && os.IsPermission(err) {
+		return nil
+	}
+	return err
+}
+
+// Atime returns the access time of the file described by fi, or its
+// modification time if the file system does not provide one.
+func Atime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return statAtime(st)
+	}
+	return fi.ModTime()
+}
+
+// SetTimes sets the access and modification times of p to those described
+// by fi without following p if it is a symbolic link.
+func SetTimes(p string, fi os.FileInfo) error {
+	return Chtimes(p, At(Atime(fi)), At(fi.ModTime()), false)
+}
+
+// Chtimes changes the access and modification times of p. Unlike
+// os.Chtimes, a time may be left unchanged or set to the current time, and
+// if deref is false and p is a symbolic link then the times of the link
+// itself are changed.
+func Chtimes(p string, atime, mtime Time, deref bool) error {
+	ts := []unix.Timespec{timespec(atime), timespec(mtime)}
+	flags := unix.AT_SYMLINK_NOFOLLOW
+	if deref {
+		flags = 0
+	}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, p, ts, flags); err != nil {
+		return &os.PathError{Op: "utimensat", Path: p, Err: err}
+	}
+	return nil
+}
+
+// timespec returns the timespec of t for utimensat.
+func timespec(t Time) unix.Timespec {
+	switch t.kind {
+	case timeOmit:
+		return unix.Timespec{Nsec: utimeOmit}
+	case timeNow:
+		return unix.Timespec{Nsec: utimeNow}
+	}
+	return unix.NsecToTimespec(t.t.UnixNano())
+}
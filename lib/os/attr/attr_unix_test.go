@@ -0,0 +1,175 @@
+// +build !windows
+
+package attr
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "attr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestUnixID(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	a, b, c := path.Join(dir, "a"), path.Join(dir, "b"), path.Join(dir, "c")
+	assert.NoError(t, ioutil.WriteFile(a, []byte("a"), 0644))
+	assert.NoError(t, os.Link(a, b))
+	assert.NoError(t, ioutil.WriteFile(c, []byte("c"), 0644))
+
+	fiA, _ := os.Stat(a)
+	fiB, _ := os.Stat(b)
+	fiC, _ := os.Stat(c)
+
+	idA, nlink, ok := ID(fiA)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), nlink)
+
+	idB, _, _ := ID(fiB)
+	assert.Equal(t, idA, idB)
+
+	idC, nlink, _ := ID(fiC)
+	assert.NotEqual(t, idA, idC)
+	assert.Equal(t, uint64(1), nlink)
+}
+
+func TestUnixSetTimes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	from, to := path.Join(dir, "from"), path.Join(dir, "to")
+	assert.NoError(t, ioutil.WriteFile(from, []byte("from"), 0644))
+	assert.NoError(t, ioutil.WriteFile(to, []byte("to"), 0644))
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 7000, time.UTC)
+	assert.NoError(t, os.Chtimes(from, mtime, mtime))
+
+	fi, err := os.Stat(from)
+	assert.NoError(t, err)
+	assert.NoError(t, SetTimes(to, fi))
+
+	fi, err = os.Stat(to)
+	assert.NoError(t, err)
+	assert.True(t, mtime.Equal(fi.ModTime()))
+}
+
+func TestUnixSetTimesSymlink(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	target, link := path.Join(dir, "target"), path.Join(dir, "link")
+	assert.NoError(t, ioutil.WriteFile(target, []byte("target"), 0644))
+	assert.NoError(t, os.Symlink("target", link))
+
+	before, err := os.Stat(target)
+	assert.NoError(t, err)
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(target, mtime, mtime))
+	fi, err := os.Stat(target)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chtimes(target, before.ModTime(), before.ModTime()))
+
+	// the link itself gets the times, not the file it refers to
+	assert.NoError(t, SetTimes(link, fi))
+
+	lfi, err := os.Lstat(link)
+	assert.NoError(t, err)
+	assert.True(t, mtime.Equal(lfi.ModTime()))
+
+	tfi, err := os.Stat(target)
+	assert.NoError(t, err)
+	assert.True(t, before.ModTime().Equal(tfi.ModTime()))
+}
+
+func TestUnixChtimes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	target, link := path.Join(dir, "target"), path.Join(dir, "link")
+	assert.NoError(t, ioutil.WriteFile(target, []byte("target"), 0644))
+	assert.NoError(t, os.Symlink("target", link))
+
+	atime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	mtime := time.Date(2002, 3, 4, 5, 6, 7, 0, time.UTC)
+	assert.NoError(t, Chtimes(link, At(atime), At(mtime), true))
+
+	fi, err := os.Stat(target)
+	assert.NoError(t, err)
+	assert.True(t, atime.Equal(Atime(fi)))
+	assert.True(t, mtime.Equal(fi.ModTime()))
+
+	assert.NoError(t, Chtimes(link, At(mtime), At(atime), false))
+
+	lfi, err := os.Lstat(link)
+	assert.NoError(t, err)
+	assert.True(t, mtime.Equal(Atime(lfi)))
+	assert.True(t, atime.Equal(lfi.ModTime()))
+
+	fi, err = os.Stat(target)
+	assert.NoError(t, err)
+	assert.True(t, mtime.Equal(fi.ModTime()))
+}
+
+func TestUnixChtimesOmitNow(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	p := path.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(p, []byte("file"), 0644))
+
+	atime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	mtime := time.Date(2002, 3, 4, 5, 6, 7, 0, time.UTC)
+	assert.NoError(t, Chtimes(p, At(atime), At(mtime), true))
+
+	before := time.Now().Add(-time.Second)
+	assert.NoError(t, Chtimes(p, Omit, Now, true))
+
+	fi, err := os.Stat(p)
+	assert.NoError(t, err)
+	assert.True(t, atime.Equal(Atime(fi)))
+	assert.True(t, fi.ModTime().After(before))
+
+	assert.NoError(t, Chtimes(p, At(mtime), Omit, true))
+	fi, err = os.Stat(p)
+	assert.NoError(t, err)
+	assert.True(t, mtime.Equal(Atime(fi)))
+	assert.True(t, fi.ModTime().After(before))
+}
+
+func TestUnixLchown(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	p := path.Join(dir, "p")
+	assert.NoError(t, ioutil.WriteFile(p, []byte("p"), 0644))
+
+	fi, err := os.Stat(p)
+	assert.NoError(t, err)
+	assert.NoError(t, Lchown(p, fi))
+}
+
+func TestUnixOwner(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	fi, err := os.Stat(dir)
+	assert.NoError(t, err)
+
+	uid, gid, ok := Owner(fi)
+	assert.True(t, ok)
+	assert.Equal(t, os.Getuid(), uid)
+	assert.Equal(t, os.Getegid(), gid)
+}
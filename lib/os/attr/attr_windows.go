@@ -0,0 +1,62 @@
+package attr
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func ID(fi os.FileInfo) (FileID, uint64, bool) {
+	return FileID{}, 0, false
+}
+
+func Owner(fi os.FileInfo) (int, int, bool) {
+	return -1, -1, false
+}
+
+func Lchown(p string, fi os.FileInfo) error {
+	return nil
+}
+
+func Atime(fi os.FileInfo) time.Time {
+	if d, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, d.LastAccessTime.Nanoseconds())
+	}
+	return fi.ModTime()
+}
+
+func SetTimes(p string, fi os.FileInfo) error {
+	return os.Chtimes(p, Atime(fi), fi.ModTime())
+}
+
+// Chtimes cannot change the times of a symbolic link itself on Windows.
+func Chtimes(p string, atime, mtime Time, deref bool) error {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if !deref {
+			return &os.PathError{
+				Op: "chtimes", Path: p, Err: syscall.EWINDOWS}
+		}
+		if fi, err = os.Stat(p); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	return os.Chtimes(p,
+		resolve(atime, Atime(fi), now), resolve(mtime, fi.ModTime(), now))
+}
+
+// resolve returns the time t is, given the current time of the file, old,
+// and the current time.
+func resolve(t Time, old, now time.Time) time.Time {
+	switch t.kind {
+	case timeOmit:
+		return old
+	case timeNow:
+		return now
+	}
+	return t.t
+}
\ No newline at end of file
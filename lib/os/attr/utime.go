@@ -0,0 +1,13 @@
+// +build aix dragonfly freebsd linux openbsd solaris
+
+package attr
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// the special nanoseconds of utimensat
+const (
+	utimeNow  = unix.UTIME_NOW
+	utimeOmit = unix.UTIME_OMIT
+)
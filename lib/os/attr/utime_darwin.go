@@ -0,0 +1,7 @@
+package attr
+
+// the special nanoseconds of utimensat, from <sys/stat.h>
+const (
+	utimeNow  = -1
+	utimeOmit = -2
+)
@@ -0,0 +1,59 @@
+// +build darwin linux
+
+package attr
+
+import (
+	"bytes"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// CopyXattrs copies the extended attributes of from to to. If isLink is
+// true then from and to are symbolic links, and the attributes of the links
+// themselves are copied. Extended attributes are silently dropped when the
+// destination file system does not support them.
+func CopyXattrs(from, to string, isLink bool) error {
+	list, get := unix.Llistxattr, unix.Lgetxattr
+	if !isLink {
+		list, get = unix.Listxattr, unix.Getxattr
+	}
+
+	sz, err := list(from, nil)
+	if err != nil || sz == 0 {
+		return xattrErr("listxattr", from, err)
+	}
+	buf := make([]byte, sz)
+	if sz, err = list(from, buf); err != nil {
+		return xattrErr("listxattr", from, err)
+	}
+
+	for _, name := range bytes.Split(buf[:sz], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		n := string(name)
+
+		sz, err := get(from, n, nil)
+		if err != nil {
+			return xattrErr("getxattr", from, err)
+		}
+		val := make([]byte, sz)
+		if sz, err = get(from, n, val); err != nil {
+			return xattrErr("getxattr", from, err)
+		}
+
+		if err := unix.Lsetxattr(to, n, val[:sz], 0); err != nil {
+			return xattrErr("setxattr", to, err)
+		}
+	}
+
+	return nil
+}
+
+func xattrErr(op, p string, err error) error {
+	if err == nil || err == unix.ENOTSUP {
+		return nil
+	}
+	return &os.PathError{Op: op, Path: p, Err: err}
+}
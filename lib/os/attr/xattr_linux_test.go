@@ -0,0 +1,31 @@
+package attr
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestLinuxCopyXattrs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	from, to := path.Join(dir, "from"), path.Join(dir, "to")
+	assert.NoError(t, ioutil.WriteFile(from, []byte("from"), 0644))
+	assert.NoError(t, ioutil.WriteFile(to, []byte("to"), 0644))
+
+	if err := unix.Setxattr(from, "user.gnixutils", []byte("1"), 0); err != nil {
+		t.Skipf("extended attributes are not supported: %v", err)
+	}
+
+	assert.NoError(t, CopyXattrs(from, to, false))
+
+	buf := make([]byte, 16)
+	sz, err := unix.Getxattr(to, "user.gnixutils", buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(buf[:sz]))
+}
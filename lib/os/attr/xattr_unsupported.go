@@ -0,0 +1,7 @@
+// +build !darwin,!linux
+
+package attr
+
+func CopyXattrs(from, to string, isLink bool) error {
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+Package backup makes backups of files that are about to be replaced, using
+the same naming schemes as the --backup option of GNU cp, mv and install.
+*/
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+// Control is a backup control method. It implements flag.Value so that it
+// can be given as --backup or --backup=CONTROL; the method is Existing if
+// no value is given.
+type Control int
+
+const (
+	// None never makes backups.
+	None Control = iota
+
+	// Numbered always makes numbered backups, FILE.~N~.
+	Numbered
+
+	// Existing makes numbered backups of files that already have them, and
+	// simple backups of the others.
+	Existing
+
+	// Simple always makes simple backups, FILE followed by the suffix.
+	Simple
+)
+
+// DefaultSuffix is the suffix of simple backups.
+const DefaultSuffix = "~"
+
+// ParseControl parses the name of a backup control method. The names are
+// those accepted by GNU coreutils: none or off, numbered or t, existing or
+// nil, and simple or never.
+func ParseControl(s string) (Control, error) {
+	switch s {
+	case "none", "off":
+		return None, nil
+	case "numbered", "t":
+		return Numbered, nil
+	case "existing", "nil":
+		return Existing, nil
+	case "simple", "never":
+		return Simple, nil
+	}
+	return None, fmt.Errorf(
+		"invalid backup type %q; valid types are none, numbered, "+
+			"existing and simple", s)
+}
+
+func (c *Control) String() string {
+	if c == nil {
+		return ""
+	}
+	switch *c {
+	case Numbered:
+		return "numbered"
+	case Existing:
+		return "existing"
+	case Simple:
+		return "simple"
+	}
+	return "none"
+}
+
+// Set parses the method. The value "true" is what the flag package passes
+// when the flag is given without a value.
+func (c *Control) Set(s string) error {
+	if s == "true" {
+		*c = Existing
+		return nil
+	}
+	v, err := ParseControl(s)
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// IsBoolFlag allows the flag to be given without a value.
+func (c *Control) IsBoolFlag() bool {
+	return true
+}
+
+// Make renames the file p to the name of its backup, returning the name.
+// An empty name is returned if c is None.
+func Make(p string, c Control, suffix string) (string, error) {
+	name, err := Name(p, c, suffix)
+	if err != nil || name == "" {
+		return "", err
+	}
+	if err := os.Rename(p, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Name returns the name of the backup of the file p according to the
+// control method c. An empty name is returned if c is None.
+func Name(p string, c Control, suffix string) (string, error) {
+	switch c {
+	case Numbered:
+		return numberedName(p, suffix, true)
+	case Existing:
+		return numberedName(p, suffix, false)
+	case Simple:
+		return p + suffix, nil
+	}
+	return "", nil
+}
+
+// numberedName returns p.~N~, where N is one greater than the highest
+// numbered backup of p. If there are no numbered backups of p and always
+// is false then the simple backup name is returned instead.
+func numberedName(p, suffix string, always bool) (string, error) {
+	dir, base := path.Split(p)
+	if dir == "" {
+		dir = "."
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return "", err
+	}
+	defer d.Close()
+
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return "", err
+	}
+
+	rx := regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `\.~(\d+)~$`)
+	highest := 0
+	for _, n := range names {
+		m := rx.FindStringSubmatch(n)
+		if m == nil {
+			continue
+		}
+		if v, err := strconv.Atoi(m[1]); err == nil && v > highest {
+			highest = v
+		}
+	}
+
+	if highest == 0 && !always {
+		return p + suffix, nil
+	}
+	return fmt.Sprintf("%s.~%d~", p, highest+1), nil
+}
@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseControl(t *testing.T) {
+	for s, c := range map[string]Control{
+		"none":     None,
+		"off":      None,
+		"numbered": Numbered,
+		"t":        Numbered,
+		"existing": Existing,
+		"nil":      Existing,
+		"simple":   Simple,
+		"never":    Simple,
+	} {
+		v, err := ParseControl(s)
+		assert.NoError(t, err)
+		assert.Equal(t, c, v, s)
+	}
+
+	_, err := ParseControl("sometimes")
+	assert.Error(t, err)
+}
+
+func TestControlFlag(t *testing.T) {
+	var c Control
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.Var(&c, "backup", "")
+
+	assert.NoError(t, fs.Parse([]string{"--backup"}))
+	assert.Equal(t, Existing, c)
+
+	assert.NoError(t, fs.Parse([]string{"--backup=numbered"}))
+	assert.Equal(t, Numbered, c)
+	assert.Equal(t, "numbered", c.String())
+}
+
+func TestName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := path.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(p, []byte("file"), 0644))
+
+	name, err := Name(p, None, DefaultSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, "", name)
+
+	name, err = Name(p, Simple, ".bak")
+	assert.NoError(t, err)
+	assert.Equal(t, p+".bak", name)
+
+	name, err = Name(p, Existing, DefaultSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, p+"~", name)
+
+	name, err = Name(p, Numbered, DefaultSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, p+".~1~", name)
+
+	assert.NoError(t, ioutil.WriteFile(p+".~9~", []byte("9"), 0644))
+
+	name, err = Name(p, Existing, DefaultSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, p+".~10~", name)
+}
+
+func TestMake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := path.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(p, []byte("file"), 0644))
+
+	name, err := Make(p, Numbered, DefaultSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, p+".~1~", name)
+
+	_, err = os.Stat(p)
+	assert.True(t, os.IsNotExist(err))
+
+	buf, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "file", string(buf))
+}
@@ -0,0 +1,23 @@
+package group
+
+import (
+	"github.com/akutz/gnixutils/lib/os/internal/lru"
+)
+
+// cacheSize is the number of groups LookupGroupID remembers.
+const cacheSize = 1024
+
+// groupIDCache holds the results of LookupGroupID.
+var groupIDCache = lru.New(cacheSize)
+
+// copy returns a copy of g so that callers cannot change the cached group.
+func (g *Group) copy() *Group {
+	if g == nil {
+		return nil
+	}
+	c := *g
+	if g.Members != nil {
+		c.Members = append([]string(nil), g.Members...)
+	}
+	return &c
+}
@@ -0,0 +1,41 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/akutz/gnixutils/lib/os/internal/lru"
+)
+
+func TestGroupCopy(t *testing.T) {
+	g := &Group{ID: "1", Name: "one", Members: []string{"alice"}}
+	c := g.copy()
+	g.Members[0] = "mallory"
+	g.Name = "changed"
+	assert.Equal(t, &Group{ID: "1", Name: "one", Members: []string{"alice"}},
+		c)
+	assert.Nil(t, (*Group)(nil).copy())
+}
+
+func TestLookupGroupIDCached(t *testing.T) {
+	old := groupIDCache
+	groupIDCache = lru.New(cacheSize)
+	defer func() { groupIDCache = old }()
+
+	grp, err := LookupGroupID("0")
+	assert.NoError(t, err)
+	assert.NotNil(t, groupIDCache.Get("0"))
+
+	// callers cannot change the cached group
+	grp.Name = "changed"
+	again, err := LookupGroupID("0")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "changed", again.Name)
+
+	_, err = LookupGroupID("4294967294")
+	assert.Equal(t, UnknownGroupIDError("4294967294"), err)
+	if e := groupIDCache.Get("4294967294"); assert.NotNil(t, e) {
+		assert.Equal(t, UnknownGroupIDError("4294967294"), e.Err)
+	}
+}
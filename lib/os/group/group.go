@@ -23,6 +23,14 @@ func (e UnknownGroupIDError) Error() string {
 	return "group: unknown groupid " + string(e)
 }
 
+// UnknownGroupError is returned by LookupGroup when a group cannot be
+// found.
+type UnknownGroupError string
+
+func (e UnknownGroupError) Error() string {
+	return "group: unknown group " + string(e)
+}
+
 // Group represents a group database entry.
 //
 // On posix systems Gid contains a decimal number
@@ -33,17 +41,46 @@ type Group struct {
 
 	// Name is the group's name.
 	Name string
+
+	// Members are the names of the users who are members of the group in
+	// addition to those whose login group it is.
+	Members []string
 }
 
 // LookupGroupID looks up a group by a group's ID. If the group cannot be
-// found, the returned error is of type UnknownGroupIdError.
+// found, the returned error is of type UnknownGroupIdError. The results of
+// recent lookups are cached.
 func LookupGroupID(gid string) (*Group, error) {
+	if e := groupIDCache.Get(gid); e != nil {
+		return e.Value.(*Group).copy(), e.Err
+	}
+
 	grp, err := lookupGroupID(gid)
 	if err == ErrUnsupported {
-		return &Group{
+		grp, err = &Group{
 			ID:   gid,
 			Name: "",
 		}, nil
 	}
+
+	// other errors may not happen again
+	if _, ok := err.(UnknownGroupIDError); err == nil || ok {
+		groupIDCache.Put(gid, grp.copy(), err)
+	}
 	return grp, err
 }
+
+// LookupGroup looks up a group by name. If the group cannot be found, the
+// returned error is of type UnknownGroupError. ErrUnsupported is returned
+// if groups cannot be looked up on the current OS.
+func LookupGroup(name string) (*Group, error) {
+	return lookupGroup(name)
+}
+
+// GroupIDsForUser returns the IDs of the groups that the user with the
+// given name is a member of, including the user's login group.
+// ErrUnsupported is returned if groups cannot be looked up on the current
+// OS.
+func GroupIDsForUser(username string) ([]string, error) {
+	return groupIDsForUser(username)
+}
@@ -18,3 +18,17 @@ func TestDarwinLookupID(t *testing.T) {
 	assert.Equal(t, gid, grp.ID)
 	assert.Equal(t, name, grp.Name)
 }
+
+func TestDarwinLookupGroup(t *testing.T) {
+	grp, err := LookupGroup("wheel")
+	assert.NoError(t, err)
+	assert.NotNil(t, grp)
+	assert.Equal(t, "0", grp.ID)
+	assert.Equal(t, "wheel", grp.Name)
+}
+
+func TestDarwinGroupIDsForUser(t *testing.T) {
+	ids, err := GroupIDsForUser("root")
+	assert.NoError(t, err)
+	assert.Contains(t, ids, "0")
+}
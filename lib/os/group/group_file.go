@@ -0,0 +1,91 @@
+package group
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/akutz/gnixutils/lib/os/internal/colondb"
+)
+
+// groupFile is the group database read by the pure Go implementation.
+var groupFile = "/etc/group"
+
+// groupFields is the number of fields of an entry of a group file,
+// name:password:gid:members.
+const groupFields = 4
+
+// readGroupFile calls fn with each valid entry of the group file r until fn
+// returns true.
+func readGroupFile(r io.Reader, fn func(*Group) bool) error {
+	return colondb.Read(r, groupFields, func(fields []string) bool {
+		g, ok := parseGroup(fields)
+		return ok && fn(g)
+	})
+}
+
+// parseGroup parses the fields of an entry of a group file.
+func parseGroup(fields []string) (*Group, bool) {
+	gid, err := strconv.ParseUint(fields[2], 10, 32)
+	if err != nil {
+		return nil, false
+	}
+
+	g := &Group{
+		ID:   strconv.FormatUint(gid, 10),
+		Name: fields[0],
+	}
+	for _, m := range strings.Split(fields[3], ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			g.Members = append(g.Members, m)
+		}
+	}
+	return g, true
+}
+
+// findGroup returns the first entry in the group file p for which match
+// returns true, or notFound if there is none.
+func findGroup(
+	p string, match func(*Group) bool, notFound error) (*Group, error) {
+
+	var found *Group
+	err := colondb.ReadFile(p, groupFields, func(fields []string) bool {
+		if g, ok := parseGroup(fields); ok && match(g) {
+			found = g
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, notFound
+	}
+	return found, nil
+}
+
+// findGroupIDsForUser returns the IDs of the groups in the group file p
+// that username is a member of, starting with the user's login group gid.
+func findGroupIDsForUser(p, username, gid string) ([]string, error) {
+	ids := []string{gid}
+	seen := map[string]bool{gid: true}
+	err := colondb.ReadFile(p, groupFields, func(fields []string) bool {
+		g, ok := parseGroup(fields)
+		if !ok || seen[g.ID] {
+			return false
+		}
+		for _, m := range g.Members {
+			if m == username {
+				ids = append(ids, g.ID)
+				seen[g.ID] = true
+				break
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,97 @@
+package group
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGroupFile = `# a comment
+root:x:0:
+wheel:x:10:alice,bob
+
+staff:*:20: carol , dave,
++nis-group
+-excluded:x:30:
++
+missing-fields:x:40
+bad-gid:x:abc:
+:x:50:
+users:x:100:alice
+wheel:x:11:duplicate
+`
+
+func TestReadGroupFile(t *testing.T) {
+	var groups []*Group
+	err := readGroupFile(strings.NewReader(testGroupFile),
+		func(g *Group) bool {
+			groups = append(groups, g)
+			return false
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Group{
+		{ID: "0", Name: "root"},
+		{ID: "10", Name: "wheel", Members: []string{"alice", "bob"}},
+		{ID: "20", Name: "staff", Members: []string{"carol", "dave"}},
+		{ID: "100", Name: "users", Members: []string{"alice"}},
+		{ID: "11", Name: "wheel", Members: []string{"duplicate"}},
+	}, groups)
+}
+
+func TestReadGroupFileStop(t *testing.T) {
+	n := 0
+	err := readGroupFile(strings.NewReader(testGroupFile),
+		func(g *Group) bool {
+			n++
+			return g.Name == "wheel"
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestFindGroup(t *testing.T) {
+	p := writeGroupFile(t)
+	defer os.RemoveAll(path.Dir(p))
+
+	g, err := findGroup(p, func(g *Group) bool { return g.Name == "wheel" },
+		UnknownGroupError("wheel"))
+	assert.NoError(t, err)
+	assert.Equal(t, "10", g.ID)
+
+	_, err = findGroup(p, func(g *Group) bool { return g.ID == "30" },
+		UnknownGroupIDError("30"))
+	assert.Equal(t, UnknownGroupIDError("30"), err)
+
+	_, err = findGroup(path.Join(path.Dir(p), "none"),
+		func(g *Group) bool { return true }, nil)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFindGroupIDsForUser(t *testing.T) {
+	p := writeGroupFile(t)
+	defer os.RemoveAll(path.Dir(p))
+
+	ids, err := findGroupIDsForUser(p, "alice", "100")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"100", "10"}, ids)
+
+	ids, err = findGroupIDsForUser(p, "nobody", "65534")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"65534"}, ids)
+}
+
+func writeGroupFile(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := path.Join(dir, "group")
+	if err := ioutil.WriteFile(p, []byte(testGroupFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,33 @@
+// +build darwin dragonfly freebsd !android,linux netbsd openbsd solaris
+// +build !cgo
+
+package group
+
+import (
+	"strconv"
+
+	"github.com/akutz/gnixutils/lib/os/user"
+)
+
+func lookupGroupID(gid string) (*Group, error) {
+	if _, err := strconv.Atoi(gid); err != nil {
+		return nil, err
+	}
+	return findGroup(groupFile, func(g *Group) bool {
+		return g.ID == gid
+	}, UnknownGroupIDError(gid))
+}
+
+func lookupGroup(name string) (*Group, error) {
+	return findGroup(groupFile, func(g *Group) bool {
+		return g.Name == name
+	}, UnknownGroupError(name))
+}
+
+func groupIDsForUser(username string) ([]string, error) {
+	u, err := user.LookupUser(username)
+	if err != nil {
+		return nil, err
+	}
+	return findGroupIDsForUser(groupFile, username, u.GroupID)
+}
@@ -0,0 +1,50 @@
+// +build dragonfly freebsd !android,linux netbsd openbsd solaris
+// +build !cgo
+
+package group
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoCgoLookupID(t *testing.T) {
+	gid := "0"
+	name := "root"
+	grp, err := LookupGroupID(gid)
+	assert.NoError(t, err)
+	assert.NotNil(t, grp)
+	assert.Equal(t, gid, grp.ID)
+	assert.Equal(t, name, grp.Name)
+}
+
+func TestNoCgoLookupGroup(t *testing.T) {
+	grp, err := LookupGroup("root")
+	assert.NoError(t, err)
+	assert.NotNil(t, grp)
+	assert.Equal(t, "0", grp.ID)
+
+	_, err = LookupGroup("no-such-group")
+	assert.Equal(t, UnknownGroupError("no-such-group"), err)
+}
+
+func TestNoCgoGroupFile(t *testing.T) {
+	p := writeGroupFile(t)
+	defer os.RemoveAll(path.Dir(p))
+
+	old := groupFile
+	groupFile = p
+	defer func() { groupFile = old }()
+
+	grp, err := LookupGroupID("20")
+	assert.NoError(t, err)
+	assert.Equal(t, "staff", grp.Name)
+	assert.Equal(t, []string{"carol", "dave"}, grp.Members)
+
+	grp, err = LookupGroup("users")
+	assert.NoError(t, err)
+	assert.Equal(t, "100", grp.ID)
+}
@@ -9,6 +9,8 @@ import (
 	"strconv"
 	"syscall"
 	"unsafe"
+
+	"github.com/akutz/gnixutils/lib/os/user"
 )
 
 /*
@@ -23,6 +25,20 @@ static int mygetgrgid_r(int gid, struct group *grp,
            char *buf, size_t buflen, struct group **result) {
     return getgrgid_r(gid, grp, buf, buflen, result);
 }
+
+static int mygetgrnam_r(const char *name, struct group *grp,
+           char *buf, size_t buflen, struct group **result) {
+    return getgrnam_r(name, grp, buf, buflen, result);
+}
+
+static int mygetgrouplist(const char *user, gid_t group,
+           gid_t *groups, int *ngroups) {
+#ifdef __APPLE__
+    return getgrouplist(user, (int)group, (int *)groups, ngroups);
+#else
+    return getgrouplist(user, group, groups, ngroups);
+#endif
+}
 */
 import "C"
 
@@ -31,47 +47,104 @@ const (
 	groupBuffer
 )
 
+// maxBufferSize is the largest buffer lookupUnixGroup allocates for a
+// group entry. The buffer holds the names of the group's members, so it
+// must be large for groups with thousands of them.
+const maxBufferSize = 16 << 20
+
+// maxGroups is the largest number of groups a user may be a member of
+// that groupIDsForUser will look up.
+const maxGroups = 64 * 1024
+
 func lookupGroupID(gid string) (*Group, error) {
 	i, e := strconv.Atoi(gid)
 	if e != nil {
 		return nil, e
 	}
-	return lookupUnixGroup(i, buildGroup)
+	return lookupUnixGroup(
+		func(grp *C.struct_group,
+			buf *C.char,
+			size C.size_t,
+			result **C.struct_group) C.int {
+
+			// mygetgrgid_r is a wrapper around getgrgid_r to
+			// to avoid using gid_t because C.gid_t(gid) for
+			// unknown reasons doesn't work on linux.
+			return C.mygetgrgid_r(C.int(i), grp, buf, size, result)
+		},
+		"groupid "+gid,
+		UnknownGroupIDError(gid),
+		buildGroup)
+}
+
+func lookupGroup(name string) (*Group, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	return lookupUnixGroup(
+		func(grp *C.struct_group,
+			buf *C.char,
+			size C.size_t,
+			result **C.struct_group) C.int {
+
+			return C.mygetgrnam_r(cname, grp, buf, size, result)
+		},
+		"group "+name,
+		UnknownGroupError(name),
+		buildGroup)
 }
 
-// LookupID looks up a group name based on its group ID.
-func lookupUnixGroup(gid int, f func(*C.struct_group) *Group) (*Group, error) {
+// lookupUnixGroup looks up a group with the reentrant group database
+// function called by get, which fills in grp using the buffer buf. The
+// error notFound is returned if there is no such group, and desc describes
+// the group in other errors.
+func lookupUnixGroup(
+	get func(
+		grp *C.struct_group,
+		buf *C.char,
+		size C.size_t,
+		result **C.struct_group) C.int,
+	desc string,
+	notFound error,
+	f func(*C.struct_group) *Group) (*Group, error) {
 
 	var grp C.struct_group
 	var result *C.struct_group
 
-	buf, bufSize, err := allocBuffer(groupBuffer)
+	bufSize, err := bufferSize(groupBuffer)
 	if err != nil {
 		return nil, err
 	}
-	defer C.free(buf)
-
-	// mygetgrgid_r is a wrapper around getgrgid_r to
-	// to avoid using gid_t because C.gid_t(gid) for
-	// unknown reasons doesn't work on linux.
-	rv := C.mygetgrgid_r(C.int(gid),
-		&grp,
-		(*C.char)(buf),
-		C.size_t(bufSize),
-		&result)
-	if rv != 0 {
-		return nil, fmt.Errorf(
-			"group: lookup groupid %d: %s", gid, syscall.Errno(rv))
-	}
-	if result == nil {
-		return nil, UnknownGroupIDError(gid)
-	}
 
-	g := f(&grp)
-	return g, nil
+	for {
+		buf := C.malloc(C.size_t(bufSize))
+		rv := get(&grp, (*C.char)(buf), C.size_t(bufSize), &result)
+
+		// the entry does not fit in the buffer
+		if syscall.Errno(rv) == syscall.ERANGE && bufSize < maxBufferSize {
+			C.free(buf)
+			bufSize *= 2
+			continue
+		}
+
+		var g *Group
+		switch {
+		case rv != 0:
+			err = fmt.Errorf(
+				"group: lookup %s: %s", desc, syscall.Errno(rv))
+		case result == nil:
+			err = notFound
+		default:
+			g = f(&grp)
+		}
+		C.free(buf)
+		return g, err
+	}
 }
 
-func allocBuffer(bufType int) (unsafe.Pointer, C.long, error) {
+// bufferSize returns the initial size of the buffer for the reentrant user
+// or group database functions.
+func bufferSize(bufType int) (C.long, error) {
 	var bufSize C.long
 
 	if runtime.GOOS == "freebsd" {
@@ -91,18 +164,78 @@ func allocBuffer(bufType int) (unsafe.Pointer, C.long, error) {
 			constName = "_SC_GETGR_R_SIZE_MAX"
 		}
 		bufSize = C.sysconf(size)
-		if bufSize <= 0 || bufSize > 1<<20 {
-			return nil, bufSize,
+		if bufSize == -1 {
+			// there is no limit, so start small and grow the buffer
+			bufSize = 1024
+		}
+		if bufSize <= 0 || bufSize > maxBufferSize {
+			return bufSize,
 				fmt.Errorf("user: unreasonable %s of %d", constName, bufSize)
 		}
 	}
-	return C.malloc(C.size_t(bufSize)), bufSize, nil
+	return bufSize, nil
 }
 
 func buildGroup(grp *C.struct_group) *Group {
 	g := &Group{
-		ID:   strconv.Itoa(int(grp.gr_gid)),
-		Name: C.GoString(grp.gr_name),
+		ID:      strconv.Itoa(int(grp.gr_gid)),
+		Name:    C.GoString(grp.gr_name),
+		Members: members(grp.gr_mem),
 	}
 	return g
 }
+
+// members returns the names in the NULL-terminated array mem.
+func members(mem **C.char) []string {
+	var names []string
+	if mem == nil {
+		return names
+	}
+	for p := mem; *p != nil; p = (**C.char)(unsafe.Pointer(
+		uintptr(unsafe.Pointer(p)) + unsafe.Sizeof(*p))) {
+		names = append(names, C.GoString(*p))
+	}
+	return names
+}
+
+func groupIDsForUser(username string) ([]string, error) {
+	u, err := user.LookupUser(username)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := strconv.Atoi(u.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	cname := C.CString(username)
+	defer C.free(unsafe.Pointer(cname))
+
+	// getgrouplist fails if there are more groups than fit in the list,
+	// but not every system says how many there are
+	n := 256
+	for {
+		gids := make([]C.gid_t, n)
+		count := C.int(n)
+		rv := C.mygetgrouplist(cname, C.gid_t(gid), &gids[0], &count)
+		if rv == -1 {
+			if n >= maxGroups {
+				return nil, fmt.Errorf(
+					"group: user %s is a member of too many groups",
+					username)
+			}
+			if int(count) > n {
+				n = int(count)
+			} else {
+				n *= 2
+			}
+			continue
+		}
+
+		ids := make([]string, int(count))
+		for i := range ids {
+			ids[i] = strconv.Itoa(int(gids[i]))
+		}
+		return ids, nil
+	}
+}
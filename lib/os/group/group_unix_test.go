@@ -4,6 +4,8 @@
 package group
 
 import (
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,3 +20,43 @@ func TestUnixLookupID(t *testing.T) {
 	assert.Equal(t, gid, grp.ID)
 	assert.Equal(t, name, grp.Name)
 }
+
+func TestUnixLookupGroup(t *testing.T) {
+	grp, err := LookupGroup("root")
+	assert.NoError(t, err)
+	assert.NotNil(t, grp)
+	assert.Equal(t, "0", grp.ID)
+	assert.Equal(t, "root", grp.Name)
+
+	_, err = LookupGroup("no-such-group")
+	assert.Equal(t, UnknownGroupError("no-such-group"), err)
+}
+
+func TestUnixGroupIDsForUser(t *testing.T) {
+	ids, err := GroupIDsForUser("root")
+	assert.NoError(t, err)
+	assert.Contains(t, ids, "0")
+
+	_, err = GroupIDsForUser("no-such-user")
+	assert.Error(t, err)
+}
+
+func TestUnixMembers(t *testing.T) {
+	// find a group with members in the local group file
+	buf, err := ioutil.ReadFile("/etc/group")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) != 4 || fields[3] == "" ||
+			strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		grp, err := LookupGroup(fields[0])
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Split(fields[3], ","), grp.Members)
+		return
+	}
+	t.Skip("no group in /etc/group has members")
+}
@@ -1,8 +1,15 @@
-// +build !darwin !dragonfly !freebsd !android !linux !netbsd !openbsd !solaris
-// +build !cgo
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris android
 
 package group
 
 func lookupGroupID(gid string) (*Group, error) {
 	return nil, ErrUnsupported
 }
+
+func lookupGroup(name string) (*Group, error) {
+	return nil, ErrUnsupported
+}
+
+func groupIDsForUser(username string) ([]string, error) {
+	return nil, ErrUnsupported
+}
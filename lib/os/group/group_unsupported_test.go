@@ -1,5 +1,4 @@
-// +build !darwin !dragonfly !freebsd !android !linux !netbsd !openbsd !solaris
-// +build !cgo
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris android
 
 package group
 
@@ -11,7 +10,6 @@ import (
 
 func TestUnsupportedLookupID(t *testing.T) {
 	gid := "0"
-	name := "root"
 	grp, err := LookupGroupID(gid)
 	assert.NoError(t, err)
 	assert.NotNil(t, grp)
@@ -0,0 +1,50 @@
+/*
+Package colondb reads the colon-separated databases of Unix, such as
+/etc/passwd and /etc/group, for the pure Go implementations of packages
+group and user.
+*/
+package colondb
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxLineSize is the longest entry that is read. The entries of groups with
+// thousands of members are long.
+const maxLineSize = 16 * 1024 * 1024
+
+// Read calls fn with the fields of each entry of the database r until fn
+// returns true. Comments, blank lines, entries without n fields or with an
+// empty first field, and the +/- entries of NIS compat mode, which refer to
+// entries that are not in the file, are skipped.
+func Read(r io.Reader, n int, fn func(fields []string) bool) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxLineSize)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == '+' || line[0] == '-' {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) != n || fields[0] == "" {
+			continue
+		}
+		if fn(fields) {
+			return nil
+		}
+	}
+	return s.Err()
+}
+
+// ReadFile calls Read with the database file p.
+func ReadFile(p string, n int, fn func(fields []string) bool) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Read(f, n, fn)
+}
@@ -0,0 +1,80 @@
+package colondb
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testFile = `# a comment
+a:1:2
+
+  b:3:4  
++nis
+-excluded:5:6
++
+c:7
+:8:9
+d:10:11:12
+e:13:14
+`
+
+func TestRead(t *testing.T) {
+	var got [][]string
+	err := Read(strings.NewReader(testFile), 3, func(f []string) bool {
+		got = append(got, f)
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"a", "1", "2"},
+		{"b", "3", "4"},
+		{"e", "13", "14"},
+	}, got)
+}
+
+func TestReadStop(t *testing.T) {
+	n := 0
+	err := Read(strings.NewReader(testFile), 3, func(f []string) bool {
+		n++
+		return f[0] == "b"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestReadLongLine(t *testing.T) {
+	long := "big:x:" + strings.Repeat("m,", 100*1024) + "\nsmall:x:y\n"
+	var names []string
+	err := Read(strings.NewReader(long), 3, func(f []string) bool {
+		names = append(names, f[0])
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"big", "small"}, names)
+}
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "colondb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := path.Join(dir, "db")
+	assert.NoError(t, ioutil.WriteFile(p, []byte(testFile), 0644))
+
+	n := 0
+	assert.NoError(t, ReadFile(p, 3, func(f []string) bool {
+		n++
+		return false
+	}))
+	assert.Equal(t, 3, n)
+
+	err = ReadFile(path.Join(dir, "none"), 3, nil)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,77 @@
+/*
+Package lru is the cache of lookups shared by packages group and user.
+Commands such as tar look up the owner and group of every file they visit,
+and most files share a handful of them.
+*/
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is a concurrency-safe cache of lookup results that evicts the least
+// recently used entry when it is full.
+type Cache struct {
+	sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+// Entry is the cached result of a lookup.
+type Entry struct {
+	Key   string
+	Value interface{}
+	Err   error
+}
+
+// New returns an empty cache that holds up to size entries.
+func New(size int) *Cache {
+	return &Cache{
+		size:  size,
+		order: list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+// Get returns the cached result of the lookup of key, or nil if it is not
+// cached. Values are shared, so callers that hand them out must copy them.
+func (c *Cache) Get(key string) *Entry {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(e)
+	ce := *e.Value.(*Entry)
+	return &ce
+}
+
+// Put caches the result of the lookup of key.
+func (c *Cache) Put(key string, v interface{}, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.order.MoveToFront(e)
+		e.Value = &Entry{key, v, err}
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&Entry{key, v, err})
+	if c.order.Len() > c.size {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.items, e.Value.(*Entry).Key)
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *Cache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,60 @@
+package lru
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEviction(t *testing.T) {
+	c := New(2)
+	c.Put("1", "one", nil)
+	c.Put("2", "two", nil)
+
+	// 1 is now the most recently used
+	assert.NotNil(t, c.Get("1"))
+
+	notFound := errors.New("not found")
+	c.Put("3", nil, notFound)
+	assert.Nil(t, c.Get("2"))
+	assert.Equal(t, "one", c.Get("1").Value)
+
+	e := c.Get("3")
+	if assert.NotNil(t, e) {
+		assert.Nil(t, e.Value)
+		assert.Equal(t, notFound, e.Err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	c := New(2)
+	c.Put("1", "one", nil)
+	c.Put("1", "uno", nil)
+	assert.Equal(t, "uno", c.Get("1").Value)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestConcurrent(t *testing.T) {
+	c := New(16)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				k := strconv.Itoa((i + j) % 32)
+				if e := c.Get(k); e != nil {
+					assert.Equal(t, k, e.Value)
+					continue
+				}
+				c.Put(k, k, nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 16, c.Len())
+	assert.Len(t, c.items, 16)
+}
@@ -0,0 +1,336 @@
+/*
+Package mode parses file modes in the forms accepted by chmod: octal modes
+such as 0755 and symbolic modes such as a+rX,u-s,g=u,o-w. A parsed mode is
+applied to the existing mode of a file, following the rules of GNU
+coreutils.
+*/
+package mode
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	setuid = 04000
+	setgid = 02000
+	sticky = 01000
+
+	rwxu = 0700
+	rwxg = 0070
+	rwxo = 0007
+
+	// all are the bits a mode may change.
+	all = 07777
+)
+
+// flag describes how the value of a change is computed.
+type flag int
+
+const (
+	// ordinary changes use their value as is.
+	ordinary flag = iota
+
+	// copyExisting changes copy the bits of the users in their value,
+	// as in g=u.
+	copyExisting
+
+	// xIfAnyX changes also affect the execute bits if the file is a
+	// directory or already has an execute bit set, as X does.
+	xIfAnyX
+)
+
+// change is one operation of a mode, such as the +rx in u+rx,g-w.
+type change struct {
+	op   byte
+	flag flag
+
+	// affected are the bits of the users the change was given for, or 0
+	// if no users were given, in which case the umask applies.
+	affected uint32
+
+	// value are the bits the operation adds, removes or sets.
+	value uint32
+
+	// mentioned are the bits the change names explicitly. The set-user-ID
+	// and set-group-ID bits of a directory are only changed if mentioned.
+	mentioned uint32
+}
+
+// Mode is a parsed file mode.
+type Mode struct {
+	changes []change
+}
+
+// Parse parses an octal or symbolic mode.
+//
+// An octal mode sets all of the permission bits. If it has fewer than five
+// digits then the set-user-ID and set-group-ID bits of a directory are kept
+// unless the mode sets them.
+//
+// A symbolic mode is a comma-separated list of clauses of the form
+// [ugoa]*([-+=]([rwxXst]*|[ugo]))+ or [-+=][0-7]+. A clause that does not
+// name its users applies to all of them, but bits set in the umask are not
+// changed by it.
+func Parse(s string) (*Mode, error) {
+	if s == "" {
+		return nil, invalid(s)
+	}
+
+	if isOctal(s[0]) {
+		v, n, ok := parseOctal(s)
+		if !ok || n != len(s) {
+			return nil, invalid(s)
+		}
+		mentioned := uint32(all)
+		if n < 5 {
+			mentioned = v&(setuid|setgid) | sticky | 0777
+		}
+		return &Mode{[]change{{
+			op:        '=',
+			flag:      ordinary,
+			affected:  all,
+			value:     v,
+			mentioned: mentioned,
+		}}}, nil
+	}
+
+	m := &Mode{}
+	i := 0
+	for {
+		var affected uint32
+	who:
+		for ; ; i++ {
+			if i == len(s) {
+				return nil, invalid(s)
+			}
+			switch s[i] {
+			case 'u':
+				affected |= setuid | rwxu
+			case 'g':
+				affected |= setgid | rwxg
+			case 'o':
+				affected |= sticky | rwxo
+			case 'a':
+				affected |= all
+			case '=', '+', '-':
+				break who
+			default:
+				return nil, invalid(s)
+			}
+		}
+
+		for i < len(s) && isOp(s[i]) {
+			c := change{op: s[i], flag: copyExisting, affected: affected}
+			i++
+
+			var mentioned uint32
+			switch {
+			case i < len(s) && isOctal(s[i]):
+				v, n, ok := parseOctal(s[i:])
+				i += n
+				if !ok || affected != 0 || i < len(s) && s[i] != ',' {
+					return nil, invalid(s)
+				}
+				c.affected, mentioned = all, all
+				c.value = v
+				c.flag = ordinary
+			case i < len(s) && s[i] == 'u':
+				c.value = rwxu
+				i++
+			case i < len(s) && s[i] == 'g':
+				c.value = rwxg
+				i++
+			case i < len(s) && s[i] == 'o':
+				c.value = rwxo
+				i++
+			default:
+				c.flag = ordinary
+			perms:
+				for ; i < len(s); i++ {
+					switch s[i] {
+					case 'r':
+						c.value |= 0444
+					case 'w':
+						c.value |= 0222
+					case 'x':
+						c.value |= 0111
+					case 'X':
+						c.flag = xIfAnyX
+					case 's':
+						c.value |= setuid | setgid
+					case 't':
+						c.value |= sticky
+					default:
+						break perms
+					}
+				}
+			}
+
+			switch {
+			case mentioned != 0:
+				c.mentioned = mentioned
+			case affected != 0:
+				c.mentioned = affected & c.value
+			default:
+				c.mentioned = c.value
+			}
+			m.changes = append(m.changes, c)
+		}
+
+		if i == len(s) {
+			return m, nil
+		}
+		if s[i] != ',' {
+			return nil, invalid(s)
+		}
+		i++
+	}
+}
+
+// Apply returns the result of applying m to the mode old of a file. The
+// umask limits the clauses that do not name their users. Only the
+// permission, set-user-ID, set-group-ID and sticky bits of the result
+// differ from old.
+func (m *Mode) Apply(old os.FileMode, umask os.FileMode) os.FileMode {
+	mode := ToUnix(old)
+	dir := old.IsDir()
+	mask := ToUnix(umask)
+
+	for _, c := range m.changes {
+		var omit uint32
+		if dir {
+			omit = (setuid | setgid) &^ c.mentioned
+		}
+		value := c.value
+
+		switch c.flag {
+		case copyExisting:
+			value &= mode
+			if value&0444 != 0 {
+				value |= 0444
+			}
+			if value&0222 != 0 {
+				value |= 0222
+			}
+			if value&0111 != 0 {
+				value |= 0111
+			}
+		case xIfAnyX:
+			if mode&0111 != 0 || dir {
+				value |= 0111
+			}
+		}
+
+		if c.affected != 0 {
+			value &= c.affected
+		} else {
+			value &^= mask
+		}
+		value &^= omit
+
+		switch c.op {
+		case '=':
+			var preserved uint32
+			if c.affected != 0 {
+				preserved = ^c.affected
+			}
+			preserved |= omit
+			mode = mode&preserved | value
+		case '+':
+			mode |= value
+		case '-':
+			mode &^= value
+		}
+	}
+
+	return old&^(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky) |
+		FromUnix(mode)
+}
+
+// ToUnix returns the permission, set-user-ID, set-group-ID and sticky bits
+// of m as they are represented by Unix, such as 04755.
+func ToUnix(m os.FileMode) uint32 {
+	v := uint32(m.Perm())
+	if m&os.ModeSetuid != 0 {
+		v |= setuid
+	}
+	if m&os.ModeSetgid != 0 {
+		v |= setgid
+	}
+	if m&os.ModeSticky != 0 {
+		v |= sticky
+	}
+	return v
+}
+
+// FromUnix returns the os.FileMode with the Unix mode bits v.
+func FromUnix(v uint32) os.FileMode {
+	m := os.FileMode(v & 0777)
+	if v&setuid != 0 {
+		m |= os.ModeSetuid
+	}
+	if v&setgid != 0 {
+		m |= os.ModeSetgid
+	}
+	if v&sticky != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
+
+// String returns the permission bits of m as ls shows them, such as
+// rwsr-xr-x.
+func String(m os.FileMode) string {
+	const rwx = "rwxrwxrwx"
+	b := []byte("---------")
+	for i := range b {
+		if m&(1<<uint(8-i)) != 0 {
+			b[i] = rwx[i]
+		}
+	}
+	special := []struct {
+		mode os.FileMode
+		i    int
+		c    byte
+	}{
+		{os.ModeSetuid, 2, 's'},
+		{os.ModeSetgid, 5, 's'},
+		{os.ModeSticky, 8, 't'},
+	}
+	for _, s := range special {
+		if m&s.mode == 0 {
+			continue
+		}
+		if b[s.i] == 'x' {
+			b[s.i] = s.c
+		} else {
+			b[s.i] = s.c - 'a' + 'A'
+		}
+	}
+	return string(b)
+}
+
+func parseOctal(s string) (uint32, int, bool) {
+	var v uint32
+	n := 0
+	for ; n < len(s) && isOctal(s[n]); n++ {
+		v = v*8 + uint32(s[n]-'0')
+		if v > all {
+			return 0, n, false
+		}
+	}
+	return v, n, true
+}
+
+func isOctal(c byte) bool {
+	return '0' <= c && c <= '7'
+}
+
+func isOp(c byte) bool {
+	return c == '=' || c == '+' || c == '-'
+}
+
+func invalid(s string) error {
+	return fmt.Errorf("invalid mode: %q", s)
+}
@@ -0,0 +1,134 @@
+package mode
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"", "u", "a", "x", "u+q", "A+x", " u+x", "u+x ",
+		"u+x,", ",u+x", "u+x,,g+w", "o=t,u",
+		"8", "0758", "77777", "17777", "-10000",
+		"u=755", "=755x", "=7+x", "g=ur", "g=uo",
+	} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseValid(t *testing.T) {
+	for _, s := range []string{
+		"+", "-", "=", "+-", "u++x", "u=+x", "ua+w", "g=u+x",
+		"=0", "+07777", "a+rX,u-s,g=u,o-w",
+	} {
+		_, err := Parse(s)
+		assert.NoError(t, err, s)
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, tt := range []struct {
+		mode  string
+		old   os.FileMode
+		umask os.FileMode
+		want  os.FileMode
+	}{
+		{"755", 0644, 022, 0755},
+		{"u=rwx,g=rx,o=", 0, 022, 0750},
+		{"a+rX", 0600, 022, 0644},
+		{"a+rX", 0700, 022, 0755},
+		{"a+rX", os.ModeDir | 0600, 022, os.ModeDir | 0755},
+		{"+w", 0444, 022, 0644},
+		{"=r", 0777, 022, 0444},
+		{"=w", 0777, 022, 0200},
+		{"g=u", 0640, 0, 0660},
+		{"o-w", 0666, 0, 0664},
+		{"u+s,g+s,o+t", 0755, 022,
+			os.ModeSetuid | os.ModeSetgid | os.ModeSticky | 0755},
+		{"u-s", os.ModeSetuid | 0755, 022, 0755},
+
+		// directories keep their set-group-ID bit unless it is mentioned
+		{"755", os.ModeDir | os.ModeSetgid | 0700, 022,
+			os.ModeDir | os.ModeSetgid | 0755},
+		{"00755", os.ModeDir | os.ModeSetgid | 0700, 022,
+			os.ModeDir | 0755},
+		{"755", os.ModeSetgid | 0700, 022, 0755},
+	} {
+		m, err := Parse(tt.mode)
+		if !assert.NoError(t, err, tt.mode) {
+			continue
+		}
+		assert.Equal(t, tt.want, m.Apply(tt.old, tt.umask), tt.mode)
+	}
+}
+
+// TestApplyGNU compares the results of Apply with those of GNU chmod for
+// many combinations of modes, initial modes, file types and umasks.
+func TestApplyGNU(t *testing.T) {
+	f, err := os.Open("testdata/chmod.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n := 0
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 5 {
+			t.Fatalf("invalid line %q", line)
+		}
+
+		m, err := Parse(fields[0])
+		if !assert.NoError(t, err, line) {
+			continue
+		}
+		old := FromUnix(parseUnix(t, fields[1]))
+		if fields[2] == "d" {
+			old |= os.ModeDir
+		}
+		umask := FromUnix(parseUnix(t, fields[3]))
+		want := FromUnix(parseUnix(t, fields[4])) | old&os.ModeDir
+
+		assert.Equal(t, want, m.Apply(old, umask), line)
+		n++
+	}
+	assert.NoError(t, s.Err())
+	assert.True(t, n > 1000)
+}
+
+func parseUnix(t *testing.T, s string) uint32 {
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uint32(v)
+}
+
+func TestString(t *testing.T) {
+	for m, s := range map[os.FileMode]string{
+		0:                     "---------",
+		0644:                  "rw-r--r--",
+		os.ModeDir | 0755:     "rwxr-xr-x",
+		os.ModeSetuid | 0755:  "rwsr-xr-x",
+		os.ModeSetuid | 0644:  "rwSr--r--",
+		os.ModeSetgid | 0750:  "rwxr-s---",
+		os.ModeSetgid | 0740:  "rwxr-S---",
+		os.ModeSticky | 01777: "rwxrwxrwt",
+		os.ModeSticky | 0776:  "rwxrwxrwT",
+	} {
+		assert.Equal(t, s, String(m))
+	}
+	assert.EqualValues(t, 06755,
+		ToUnix(os.ModeSetuid|os.ModeSetgid|os.ModeDir|0755))
+}
@@ -0,0 +1,16 @@
+// +build !windows
+
+package mode
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Umask returns the file mode creation mask of the process.
+func Umask() os.FileMode {
+	m := unix.Umask(0)
+	unix.Umask(m)
+	return FromUnix(uint32(m))
+}
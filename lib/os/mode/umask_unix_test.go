@@ -0,0 +1,19 @@
+// +build !windows
+
+package mode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestUnixUmask(t *testing.T) {
+	old := unix.Umask(027)
+	defer unix.Umask(old)
+
+	assert.Equal(t, os.FileMode(027), Umask())
+	assert.Equal(t, os.FileMode(027), Umask())
+}
@@ -0,0 +1,8 @@
+package mode
+
+import "os"
+
+// Umask returns 0; Windows has no file mode creation mask.
+func Umask() os.FileMode {
+	return 0
+}
@@ -0,0 +1,149 @@
+/*
+Package partial resumes interrupted copies of files and verifies complete
+ones. It provides the --resume and --verify options of cp.
+
+A resumable copy is written to a partial file next to its destination.
+If the copy is interrupted, the partial file is kept, and a later copy
+keeps the data in it that still matches the source.
+*/
+package partial
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path"
+)
+
+// ErrChecksum is the error of Verify when a copy does not match its source.
+var ErrChecksum = errors.New("sha256 checksum does not match source")
+
+// Name returns the name of the file that a resumable copy to the path to
+// is written to until it is complete.
+func Name(to string) string {
+	dir, base := path.Split(to)
+	return path.Join(dir, "."+base+".cp-partial")
+}
+
+// Open opens the partial copy of to for a source of srcSize bytes,
+// creating it if it does not exist. If it does not exist but to does, then
+// to may be the result of an earlier, interrupted copy, so its contents are
+// copied to the new partial copy for Resume to check. to is left in place.
+// The partial copy is writable by its owner, whatever perm is, so that it
+// can be reopened if the copy is interrupted again.
+func Open(to string, srcSize int64, perm os.FileMode) (*os.File, error) {
+	name := Name(to)
+	_, err := os.Lstat(name)
+	created := os.IsNotExist(err)
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, perm|0600)
+	if err != nil || !created {
+		return f, err
+	}
+	if err := seed(f, to, srcSize); err != nil {
+		f.Close()
+		os.Remove(name)
+		return nil, err
+	}
+	return f, nil
+}
+
+// seed copies the regular file to to the new partial copy f if it is no
+// larger than the source, and so may be a prefix of it.
+func seed(f *os.File, to string, srcSize int64) error {
+	old, err := os.Open(to)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+
+	fi, err := old.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() || fi.Size() == 0 || fi.Size() > srcSize {
+		return nil
+	}
+	_, err = io.Copy(f, old)
+	return err
+}
+
+// Resume returns the number of bytes of the partial copy dst that are a
+// prefix of src, which is srcSize bytes long. The partial copy is truncated
+// if it is not a prefix of src. Both files are left positioned at the
+// returned offset.
+func Resume(dst, src *os.File, srcSize int64) (int64, error) {
+	fi, err := dst.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	n := fi.Size()
+	if n > 0 && n <= srcSize {
+		dstSum, err := sum(dst, n)
+		if err != nil {
+			return 0, err
+		}
+		srcSum, err := sum(src, n)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.Equal(dstSum, srcSum) {
+			n = 0
+		}
+	} else {
+		n = 0
+	}
+
+	if n == 0 {
+		if err := dst.Truncate(0); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := dst.Seek(n, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if _, err := src.Seek(n, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Verify reads all of dst and the first size bytes of src and returns
+// ErrChecksum, in an *os.PathError, if their contents differ.
+func Verify(dst, src *os.File, size int64) error {
+	dstSum, err := sum(dst, -1)
+	if err != nil {
+		return err
+	}
+	srcSum, err := sum(src, size)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(dstSum, srcSum) {
+		return &os.PathError{Op: "verify", Path: dst.Name(), Err: ErrChecksum}
+	}
+	return nil
+}
+
+// sum returns the SHA-256 digest of the first n bytes of f, or of all of f
+// if n is negative.
+func sum(f *os.File, n int64) ([]byte, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var r io.Reader = f
+	if n >= 0 {
+		r = io.LimitReader(f, n)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
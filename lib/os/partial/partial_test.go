@@ -0,0 +1,160 @@
+package partial
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var data = bytes.Repeat([]byte("0123456789abcdef"), 1024)
+
+// tempFiles creates a temporary directory with the source file src, which
+// contains data, and returns the directory, the source's path and the path
+// of its destination, which does not exist.
+func tempFiles(t *testing.T) (string, string, string) {
+	dir, err := ioutil.TempDir("", "partial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := path.Join(dir, "src")
+	if err := ioutil.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, src, path.Join(dir, "dst")
+}
+
+func open(t *testing.T, p string) *os.File {
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// resume opens the partial copy of dst, resumes it from src, copies the
+// rest of src and returns the offset it was resumed from.
+func resume(t *testing.T, src, dst string) int64 {
+	s := open(t, src)
+	defer s.Close()
+
+	f, err := Open(dst, int64(len(data)), 0444)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	off, err := Resume(f, s, int64(len(data)))
+	assert.NoError(t, err)
+	_, err = io.Copy(f, s)
+	assert.NoError(t, err)
+	assert.NoError(t, Verify(f, s, int64(len(data))))
+	return off
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "dir/.file.cp-partial", Name("dir/file"))
+	assert.Equal(t, ".file.cp-partial", Name("file"))
+}
+
+func TestOpenNew(t *testing.T) {
+	dir, src, dst := tempFiles(t)
+	defer os.RemoveAll(dir)
+
+	assert.EqualValues(t, 0, resume(t, src, dst))
+
+	fi, err := os.Stat(Name(dst))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0600, fi.Mode().Perm()&0600)
+	_, err = os.Stat(dst)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestResumePartial(t *testing.T) {
+	dir, src, dst := tempFiles(t)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(Name(dst), data[:1000], 0600))
+	assert.EqualValues(t, 1000, resume(t, src, dst))
+
+	buf, err := ioutil.ReadFile(Name(dst))
+	assert.NoError(t, err)
+	assert.Equal(t, data, buf)
+}
+
+func TestResumeDestination(t *testing.T) {
+	dir, src, dst := tempFiles(t)
+	defer os.RemoveAll(dir)
+
+	// the destination is copied to the partial copy and left in place
+	assert.NoError(t, ioutil.WriteFile(dst, data[:3000], 0400))
+	assert.EqualValues(t, 3000, resume(t, src, dst))
+
+	buf, err := ioutil.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, data[:3000], buf)
+	buf, err = ioutil.ReadFile(Name(dst))
+	assert.NoError(t, err)
+	assert.Equal(t, data, buf)
+}
+
+func TestResumeMismatch(t *testing.T) {
+	dir, src, dst := tempFiles(t)
+	defer os.RemoveAll(dir)
+
+	prefix := append([]byte{}, data[:1000]...)
+	prefix[500] = 'x'
+	assert.NoError(t, ioutil.WriteFile(Name(dst), prefix, 0600))
+	assert.EqualValues(t, 0, resume(t, src, dst))
+
+	buf, err := ioutil.ReadFile(Name(dst))
+	assert.NoError(t, err)
+	assert.Equal(t, data, buf)
+}
+
+func TestResumeLarger(t *testing.T) {
+	dir, src, dst := tempFiles(t)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(
+		Name(dst), append(data, 'x'), 0600))
+	assert.EqualValues(t, 0, resume(t, src, dst))
+
+	buf, err := ioutil.ReadFile(Name(dst))
+	assert.NoError(t, err)
+	assert.Equal(t, data, buf)
+}
+
+func TestVerify(t *testing.T) {
+	dir, src, dst := tempFiles(t)
+	defer os.RemoveAll(dir)
+
+	s := open(t, src)
+	defer s.Close()
+
+	for _, tt := range []struct {
+		data []byte
+		ok   bool
+	}{
+		{data, true},
+		{data[:len(data)-1], false},
+		{append(append([]byte{}, data...), 'x'), false},
+		{append([]byte("x"), data[1:]...), false},
+	} {
+		assert.NoError(t, ioutil.WriteFile(dst, tt.data, 0600))
+		d := open(t, dst)
+		err := Verify(d, s, int64(len(data)))
+		d.Close()
+		if tt.ok {
+			assert.NoError(t, err)
+			continue
+		}
+		if assert.Error(t, err) {
+			assert.Equal(t, ErrChecksum, err.(*os.PathError).Err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package shred
+
+import (
+	"os"
+	"path"
+	"sync"
+	"syscall"
+)
+
+// dirLocks serializes the renames of files in the same directory where the
+// renames cannot check for an existing file atomically.
+var dirLocks = struct {
+	sync.Mutex
+	m map[string]*dirLock
+}{m: map[string]*dirLock{}}
+
+type dirLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lockDir locks the directory dir for this process and returns the
+// function that unlocks it.
+func lockDir(dir string) func() {
+	dir = path.Clean(dir)
+
+	dirLocks.Lock()
+	l, ok := dirLocks.m[dir]
+	if !ok {
+		l = &dirLock{}
+		dirLocks.m[dir] = l
+	}
+	l.refs++
+	dirLocks.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		dirLocks.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(dirLocks.m, dir)
+		}
+		dirLocks.Unlock()
+	}
+}
+
+// statAndRename renames from to to unless to exists, in which case an
+// error satisfying os.IsExist is returned. The check is not atomic, so
+// callers must hold the lock of to's directory.
+func statAndRename(from, to string) error {
+	if _, err := os.Lstat(to); err == nil {
+		return &os.LinkError{
+			Op: "rename", Old: from, New: to, Err: syscall.EEXIST}
+	}
+	return os.Rename(from, to)
+}
@@ -0,0 +1,30 @@
+package shred
+
+import (
+	"os"
+	"path"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameNoReplace renames from to to unless to exists, in which case an
+// error satisfying os.IsExist is returned. The check and the rename are a
+// single atomic operation unless the file system does not support it.
+func renameNoReplace(from, to string) error {
+	err := unix.Renameat2(
+		unix.AT_FDCWD, from, unix.AT_FDCWD, to, unix.RENAME_NOREPLACE)
+	if err == unix.ENOSYS || err == unix.EINVAL {
+		defer lockDir(path.Dir(to))()
+		return statAndRename(from, to)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	return nil
+}
+
+// lockNames returns a function that does nothing, since the renames of
+// files shredded in the same directory at once are atomic on Linux.
+func lockNames(dir string) func() {
+	return func() {}
+}
@@ -0,0 +1,17 @@
+// +build !linux
+
+package shred
+
+// renameNoReplace renames from to to unless to exists, in which case an
+// error satisfying os.IsExist is returned. Callers must hold the lock of
+// to's directory.
+func renameNoReplace(from, to string) error {
+	return statAndRename(from, to)
+}
+
+// lockNames locks the directory dir while a file in it is renamed and
+// removed, so that files shredded in the same directory at once do not
+// take the same names. It returns the function that unlocks dir.
+func lockNames(dir string) func() {
+	return lockDir(dir)
+}
@@ -0,0 +1,238 @@
+/*
+Package shred overwrites the contents of files before removing them so that
+the data is harder to recover. It provides the secure deletion used by the
+shred command and by rm --shred.
+
+Overwriting is only effective on file systems that write data in place.
+Journaling, copy-on-write and log-structured file systems, as well as
+snapshots and backups, may keep copies of the data that shred cannot reach.
+*/
+package shred
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// DefaultPasses is the number of random passes used when none is given.
+const DefaultPasses = 3
+
+// Options describe how a file is shredded.
+type Options struct {
+	// Passes is the number of times the file is overwritten with random
+	// data.
+	Passes int
+
+	// Zero adds a final pass of zeros to hide the shredding.
+	Zero bool
+
+	// Truncate truncates the file to zero length after it is overwritten.
+	Truncate bool
+
+	// Remove renames the file several times to hide its name and then
+	// removes it. Remove implies Truncate.
+	Remove bool
+
+	// Force adds write permission for the owner to a file that does not
+	// have it.
+	Force bool
+
+	// Report, if not nil, is called before each pass and rename with a
+	// message describing it.
+	Report func(msg string)
+}
+
+// chunkSize is the size of the buffer used to write each pass.
+const chunkSize = 64 * 1024
+
+// nameChars are the characters used for the names a removed file is
+// renamed to.
+const nameChars = "0123456789abcdefghijklmnopqrstuvwxyz_."
+
+// File shreds the regular file p as described by o.
+func File(p string, o Options) error {
+	if o.Force {
+		if err := makeWritable(p); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := overwrite(f, p, o); err != nil {
+		f.Close()
+		return err
+	}
+	if o.Truncate || o.Remove {
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if o.Remove {
+		return remove(p, o.Report)
+	}
+	return nil
+}
+
+// makeWritable adds write permission for the owner to the regular file p.
+func makeWritable(p string) error {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() || fi.Mode().Perm()&0200 != 0 {
+		return nil
+	}
+	return os.Chmod(p, fi.Mode().Perm()|0200)
+}
+
+// overwrite writes the passes described by o over the contents of f,
+// syncing the data to disk after each pass.
+func overwrite(f *os.File, p string, o Options) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return &os.PathError{
+			Op: "shred", Path: p, Err: fmt.Errorf("not a regular file")}
+	}
+
+	total := o.Passes
+	if o.Zero {
+		total++
+	}
+
+	buf := make([]byte, chunkSize)
+	for i := 1; i <= total; i++ {
+		zero := o.Zero && i == total
+		if o.Report != nil {
+			kind := "random"
+			if zero {
+				kind = "000000"
+			}
+			o.Report(fmt.Sprintf(
+				"%s: pass %d/%d (%s)...", p, i, total, kind))
+		}
+		if err := pass(f, fi.Size(), buf, zero); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pass overwrites the first size bytes of f with random data or zeros and
+// syncs them to disk.
+func pass(f *os.File, size int64, buf []byte, zero bool) error {
+	if zero {
+		for i := range buf {
+			buf[i] = 0
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	for size > 0 {
+		n := int64(len(buf))
+		if n > size {
+			n = size
+		}
+		if !zero {
+			if _, err := io.ReadFull(rand.Reader, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if _, err := f.Write(buf[:n]); err != nil {
+			return err
+		}
+		size -= n
+	}
+	return f.Sync()
+}
+
+// remove renames p to names made of a single repeated character, each one
+// shorter than the last, so that the original name is overwritten in the
+// directory, and then removes it.
+func remove(p string, report func(string)) error {
+	dir, base := path.Split(p)
+	defer lockNames(dir)()
+	cur := p
+
+	for n := len(base); n > 0; n-- {
+		next, err := renameShorter(cur, dir, n)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			continue
+		}
+		if report != nil {
+			report(fmt.Sprintf("%s: renamed to %s", cur, next))
+		}
+		syncDir(dir)
+		cur = next
+	}
+
+	if err := os.Remove(cur); err != nil {
+		return err
+	}
+	syncDir(dir)
+	if report != nil {
+		report(fmt.Sprintf("%s: removed", p))
+	}
+	return nil
+}
+
+// renameShorter renames cur to a file in dir whose name is n copies of one
+// of the nameChars and which does not exist, and returns its path. Names
+// that are taken, including by files that other goroutines shredding in
+// dir have just been renamed to, are skipped. The returned path is empty if
+// every name is taken.
+func renameShorter(cur, dir string, n int) (string, error) {
+	for _, c := range nameChars {
+		name := strings.Repeat(string(c), n)
+		if name == "." || name == ".." {
+			continue
+		}
+		next := dir + name
+		err := renameNoReplace(cur, next)
+		if err == nil {
+			return next, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// syncDir flushes the entries of dir to disk. Not every platform can sync a
+// directory, so errors are ignored.
+func syncDir(dir string) {
+	if dir == "" {
+		dir = "."
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,154 @@
+package shred
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempFile(t *testing.T, data []byte) (string, string) {
+	dir, err := ioutil.TempDir("", "shred")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := path.Join(dir, "secret.txt")
+	if err := ioutil.WriteFile(p, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return dir, p
+}
+
+func TestFileZero(t *testing.T) {
+	data := bytes.Repeat([]byte("password"), chunkSize/4+3)
+	dir, p := tempFile(t, data)
+	defer os.RemoveAll(dir)
+
+	var msgs []string
+	err := File(p, Options{
+		Passes: 2,
+		Zero:   true,
+		Report: func(msg string) { msgs = append(msgs, msg) },
+	})
+	assert.NoError(t, err)
+
+	buf, err := ioutil.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, len(data)), buf)
+	assert.Len(t, msgs, 3)
+}
+
+func TestFileRandom(t *testing.T) {
+	data := bytes.Repeat([]byte{0}, 4096)
+	dir, p := tempFile(t, data)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, File(p, Options{Passes: 1}))
+
+	buf, err := ioutil.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Len(t, buf, len(data))
+	assert.NotEqual(t, data, buf)
+}
+
+func TestFileTruncate(t *testing.T) {
+	dir, p := tempFile(t, []byte("password"))
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, File(p, Options{Passes: 1, Truncate: true}))
+
+	fi, err := os.Stat(p)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, fi.Size())
+}
+
+func TestFileRemove(t *testing.T) {
+	dir, p := tempFile(t, []byte("password"))
+	defer os.RemoveAll(dir)
+
+	// names the file is renamed to that are already taken are skipped
+	taken := path.Join(dir, "0000000000")
+	assert.NoError(t, ioutil.WriteFile(taken, nil, 0600))
+
+	var msgs []string
+	err := File(p, Options{
+		Passes: 1,
+		Remove: true,
+		Report: func(msg string) { msgs = append(msgs, msg) },
+	})
+	assert.NoError(t, err)
+
+	_, err = os.Lstat(p)
+	assert.True(t, os.IsNotExist(err))
+
+	names, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, names, 1)
+	assert.Equal(t, "0000000000", names[0].Name())
+
+	// one pass, ten renames and the removal
+	assert.Len(t, msgs, 12)
+	assert.Equal(t, p+": renamed to "+path.Join(dir, "1111111111"), msgs[1])
+}
+
+func TestFileRemoveConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shred")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// files shredded at once in the same directory need different names
+	var ps []string
+	for i := 0; i < 64; i++ {
+		p := path.Join(dir, fmt.Sprintf("secret%02d", i))
+		assert.NoError(t, ioutil.WriteFile(p, []byte("password"), 0600))
+		ps = append(ps, p)
+	}
+
+	errs := make(chan error, len(ps))
+	var wg sync.WaitGroup
+	for _, p := range ps {
+		wg.Add(1)
+		go func(p string) {
+			defer wg.Done()
+			errs <- File(p, Options{Passes: 1, Remove: true})
+		}(p)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	names, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, names, 0)
+}
+
+func TestFileNotRegular(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shred")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	assert.Error(t, File(dir, Options{Passes: 1}))
+}
+
+func TestFileForce(t *testing.T) {
+	dir, p := tempFile(t, []byte("password"))
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Chmod(p, 0400))
+
+	assert.NoError(t, File(p, Options{Passes: 1, Zero: true, Force: true}))
+
+	buf, err := ioutil.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 8), buf)
+}
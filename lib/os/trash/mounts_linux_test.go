@@ -0,0 +1,17 @@
+package trash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinuxMountPoints(t *testing.T) {
+	assert.Contains(t, mountPoints(), "/")
+}
+
+func TestLinuxUnescapeMount(t *testing.T) {
+	assert.Equal(t, "/mnt/my disk", unescapeMount(`/mnt/my\040disk`))
+	assert.Equal(t, "/mnt/a\tb\\", unescapeMount(`/mnt/a\011b\134`))
+	assert.Equal(t, `/mnt/x\0`, unescapeMount(`/mnt/x\0`))
+}
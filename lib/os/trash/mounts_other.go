@@ -0,0 +1,9 @@
+// +build !linux
+
+package trash
+
+// mountPoints returns nil; only the home trash is listed on platforms
+// other than Linux.
+func mountPoints() []string {
+	return nil
+}
@@ -0,0 +1,407 @@
+/*
+Package trash moves files to the trash and restores them as described by the
+freedesktop.org Trash specification. Files are moved to the user's home
+trash, $XDG_DATA_HOME/Trash, or, when they are on another file system, to a
+trash directory at the top of that file system.
+*/
+package trash
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+)
+
+// Can is a trash directory.
+type Can struct {
+	// Dir is the trash directory, which contains the files and info
+	// directories.
+	Dir string
+
+	// Top is the top directory of the file system the trash directory
+	// belongs to. The original paths of files in the trash are stored
+	// relative to it. Top is empty for the home trash, which stores
+	// absolute paths.
+	Top string
+}
+
+// Item is a file in the trash.
+type Item struct {
+	// Can is the trash directory that holds the file.
+	Can Can
+
+	// Name is the name of the file in the trash.
+	Name string
+
+	// Path is the absolute path the file was deleted from.
+	Path string
+
+	// Deleted is the time the file was moved to the trash.
+	Deleted time.Time
+}
+
+// File is the path of the trashed file.
+func (i *Item) File() string {
+	return filepath.Join(i.Can.Dir, "files", i.Name)
+}
+
+// Info is the path of the file's trash info file.
+func (i *Item) Info() string {
+	return filepath.Join(i.Can.Dir, "info", i.Name+infoExt)
+}
+
+const (
+	infoExt    = ".trashinfo"
+	infoHeader = "[Trash Info]"
+	timeFormat = "2006-01-02T15:04:05"
+)
+
+// Home returns the user's home trash, $XDG_DATA_HOME/Trash. If
+// XDG_DATA_HOME is not set then $HOME/.local/share is used.
+func Home() (Can, error) {
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return Can{}, fmt.Errorf("trash: HOME is not set")
+		}
+		data = filepath.Join(home, ".local", "share")
+	}
+	return Can{Dir: filepath.Join(data, "Trash")}, nil
+}
+
+// For returns the trash directory that the file p is moved to. This is the
+// home trash unless p is on a different file system, in which case it is
+// $top/.Trash/$uid, if the administrator has provided $top/.Trash, or
+// otherwise $top/.Trash-$uid, where $top is the top directory of the file
+// system.
+func For(p string) (Can, error) {
+	home, err := Home()
+	if err != nil {
+		return Can{}, err
+	}
+
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return Can{}, err
+	}
+	dir := filepath.Dir(abs)
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return Can{}, err
+	}
+	id, _, ok := attr.ID(fi)
+	if !ok || onDevice(home.Dir, id.Dev) {
+		return home, nil
+	}
+
+	top := topDir(dir, id.Dev)
+	uid := strconv.Itoa(os.Getuid())
+
+	shared := filepath.Join(top, ".Trash")
+	if validShared(shared) {
+		return Can{Dir: filepath.Join(shared, uid), Top: top}, nil
+	}
+	return Can{Dir: filepath.Join(top, ".Trash-"+uid), Top: top}, nil
+}
+
+// onDevice returns true if the nearest existing ancestor of p, or p itself,
+// is on the device dev.
+func onDevice(p string, dev uint64) bool {
+	for {
+		if fi, err := os.Stat(p); err == nil {
+			id, _, ok := attr.ID(fi)
+			return !ok || id.Dev == dev
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return false
+		}
+		p = parent
+	}
+}
+
+// topDir returns the top directory of the file system on the device dev
+// that contains the directory dir.
+func topDir(dir string, dev uint64) string {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir || !onDevice(parent, dev) {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// validShared returns true if p is a directory that may hold the trash
+// directories of users. The specification requires it to be a directory,
+// not a symbolic link, with the sticky bit set.
+func validShared(p string) bool {
+	fi, err := os.Lstat(p)
+	return err == nil && fi.IsDir() && fi.Mode()&os.ModeSticky != 0
+}
+
+// Cans returns the trash directories of the user: the home trash followed
+// by those at the top of each mounted file system that exist.
+func Cans() ([]Can, error) {
+	home, err := Home()
+	if err != nil {
+		return nil, err
+	}
+	cans := []Can{home}
+
+	// a file system may be mounted more than once
+	seen := map[string]bool{home.Dir: true}
+
+	uid := strconv.Itoa(os.Getuid())
+	for _, top := range mountPoints() {
+		for _, dir := range []string{
+			filepath.Join(top, ".Trash", uid),
+			filepath.Join(top, ".Trash-"+uid),
+		} {
+			if seen[dir] {
+				continue
+			}
+			if fi, err := os.Lstat(dir); err == nil && fi.IsDir() {
+				seen[dir] = true
+				cans = append(cans, Can{Dir: dir, Top: top})
+			}
+		}
+	}
+	return cans, nil
+}
+
+// Put moves the file p to the trash and returns the item that describes
+// it.
+func Put(p string) (*Item, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return nil, err
+	}
+
+	can, err := For(abs)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(can.Dir, d), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	item := &Item{Can: can, Path: abs, Deleted: time.Now()}
+	info, err := createInfo(item)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(abs, item.File()); err != nil {
+		os.Remove(info)
+		return nil, err
+	}
+	return item, nil
+}
+
+// createInfo picks a name for item that is not in use in its trash
+// directory and writes the item's trash info file. The info file is
+// created exclusively, which reserves the name. The path of the info file
+// is returned.
+func createInfo(item *Item) (string, error) {
+	base := filepath.Base(item.Path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	for n := 1; ; n++ {
+		item.Name = base
+		if n > 1 {
+			item.Name = fmt.Sprintf("%s.%d%s", stem, n, ext)
+		}
+
+		if _, err := os.Lstat(item.File()); err == nil {
+			continue
+		}
+
+		info := item.Info()
+		f, err := os.OpenFile(
+			info, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		_, err = f.WriteString(formatInfo(item))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(info)
+			return "", err
+		}
+		return info, nil
+	}
+}
+
+// formatInfo returns the contents of the trash info file of item.
+func formatInfo(item *Item) string {
+	p := item.Path
+	if item.Can.Top != "" {
+		if rel, err := filepath.Rel(item.Can.Top, p); err == nil {
+			p = rel
+		}
+	}
+	return fmt.Sprintf("%s\nPath=%s\nDeletionDate=%s\n",
+		infoHeader,
+		escape(filepath.ToSlash(p)),
+		item.Deleted.Format(timeFormat))
+}
+
+// List returns the items in the trash directory can.
+func List(can Can) ([]*Item, error) {
+	d, err := os.Open(filepath.Join(can.Dir, "info"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*Item
+	for _, n := range names {
+		if !strings.HasSuffix(n, infoExt) {
+			continue
+		}
+		item := &Item{Can: can, Name: strings.TrimSuffix(n, infoExt)}
+		if err := readInfo(item); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// readInfo reads the trash info file of item, filling in its path and
+// deletion date.
+func readInfo(item *Item) error {
+	f, err := os.Open(item.Info())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var inGroup, hasPath bool
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "[") {
+			inGroup = line == infoHeader
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Path":
+			p, err := unescape(kv[1])
+			if err != nil {
+				return err
+			}
+			p = filepath.FromSlash(p)
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(item.Can.Top, p)
+			}
+			item.Path = p
+			hasPath = true
+		case "DeletionDate":
+			t, err := time.ParseInLocation(timeFormat, kv[1], time.Local)
+			if err == nil {
+				item.Deleted = t
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if !hasPath {
+		return fmt.Errorf("trash: %s: no Path", item.Info())
+	}
+	return nil
+}
+
+// Restore moves item back to the path it was deleted from, creating the
+// path's parent directories if they no longer exist. An existing file is
+// never replaced.
+func Restore(item *Item) error {
+	if _, err := os.Lstat(item.Path); err == nil {
+		return &os.PathError{
+			Op: "restore", Path: item.Path, Err: os.ErrExist}
+	}
+	if err := os.MkdirAll(filepath.Dir(item.Path), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(item.File(), item.Path); err != nil {
+		return err
+	}
+	return os.Remove(item.Info())
+}
+
+// escape percent-encodes p as a URI path, as the Path key requires.
+func escape(p string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if unreserved(c) || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// unescape decodes the percent-encoded path p.
+func unescape(p string) (string, error) {
+	var b bytes.Buffer
+	for i := 0; i < len(p); i++ {
+		if p[i] != '%' {
+			b.WriteByte(p[i])
+			continue
+		}
+		if i+2 >= len(p) {
+			return "", fmt.Errorf("trash: invalid escape in %q", p)
+		}
+		v, err := strconv.ParseUint(p[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("trash: invalid escape in %q", p)
+		}
+		b.WriteByte(byte(v))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+func unreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+		'0' <= c && c <= '9' || strings.IndexByte("-_.!~*'()", c) >= 0
+}
@@ -0,0 +1,175 @@
+package trash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "trash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, _ = filepath.EvalSymlinks(dir)
+
+	old := os.Getenv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	return dir, func() {
+		os.Setenv("XDG_DATA_HOME", old)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestHome(t *testing.T) {
+	dir, teardown := setup(t)
+	defer teardown()
+
+	can, err := Home()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "data", "Trash"), can.Dir)
+	assert.Equal(t, "", can.Top)
+
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("XDG_DATA_HOME", "")
+	os.Setenv("HOME", dir)
+	can, err = Home()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ".local", "share", "Trash"), can.Dir)
+}
+
+func TestPutListRestore(t *testing.T) {
+	dir, teardown := setup(t)
+	defer teardown()
+
+	p := filepath.Join(dir, "my file.txt")
+	assert.NoError(t, ioutil.WriteFile(p, []byte("one"), 0644))
+
+	item, err := Put(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "my file.txt", item.Name)
+	assert.Equal(t, p, item.Path)
+
+	_, err = os.Lstat(p)
+	assert.True(t, os.IsNotExist(err))
+
+	buf, err := ioutil.ReadFile(item.File())
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(buf))
+
+	info, err := ioutil.ReadFile(item.Info())
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"[Trash Info]\nPath="+escape(p)+"\nDeletionDate="+
+			item.Deleted.Format(timeFormat)+"\n",
+		string(info))
+
+	// a second file with the same name gets a new name in the trash
+	assert.NoError(t, ioutil.WriteFile(p, []byte("two"), 0644))
+	item2, err := Put(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "my file.2.txt", item2.Name)
+
+	can, _ := Home()
+	items, err := List(can)
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	for _, i := range items {
+		assert.Equal(t, p, i.Path)
+		assert.WithinDuration(t, time.Now(), i.Deleted, time.Minute)
+	}
+
+	assert.NoError(t, Restore(item2))
+	buf, err = ioutil.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(buf))
+
+	// an existing file is not replaced
+	assert.Error(t, Restore(item))
+
+	items, err = List(can)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+}
+
+func TestRestoreParents(t *testing.T) {
+	dir, teardown := setup(t)
+	defer teardown()
+
+	p := filepath.Join(dir, "a", "b", "c")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	assert.NoError(t, ioutil.WriteFile(p, nil, 0644))
+
+	item, err := Put(p)
+	assert.NoError(t, err)
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "a")))
+
+	assert.NoError(t, Restore(item))
+	_, err = os.Lstat(p)
+	assert.NoError(t, err)
+}
+
+func TestTopDirInfo(t *testing.T) {
+	dir, teardown := setup(t)
+	defer teardown()
+
+	can := Can{Dir: filepath.Join(dir, ".Trash-1000"), Top: dir}
+	assert.NoError(t, os.MkdirAll(filepath.Join(can.Dir, "info"), 0700))
+
+	item := &Item{
+		Can:     can,
+		Name:    "x",
+		Path:    filepath.Join(dir, "sub", "x"),
+		Deleted: time.Date(2004, 8, 31, 22, 32, 8, 0, time.Local),
+	}
+	info := formatInfo(item)
+	assert.Equal(t,
+		"[Trash Info]\nPath=sub/x\nDeletionDate=2004-08-31T22:32:08\n",
+		info)
+	assert.NoError(t, ioutil.WriteFile(item.Info(), []byte(info), 0600))
+
+	items, err := List(can)
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, item.Path, items[0].Path)
+		assert.True(t, item.Deleted.Equal(items[0].Deleted))
+	}
+}
+
+func TestListInvalid(t *testing.T) {
+	dir, teardown := setup(t)
+	defer teardown()
+
+	can := Can{Dir: filepath.Join(dir, "Trash")}
+	assert.NoError(t, os.MkdirAll(filepath.Join(can.Dir, "info"), 0700))
+	assert.NoError(t, ioutil.WriteFile(
+		filepath.Join(can.Dir, "info", "bad.trashinfo"),
+		[]byte("[Other]\nPath=/x\n"), 0600))
+
+	items, err := List(can)
+	assert.NoError(t, err)
+	assert.Len(t, items, 0)
+
+	items, err = List(Can{Dir: filepath.Join(dir, "none")})
+	assert.NoError(t, err)
+	assert.Len(t, items, 0)
+}
+
+func TestEscape(t *testing.T) {
+	p := "/home/user/a b/%c/é"
+	assert.Equal(t, "/home/user/a%20b/%25c/%C3%A9", escape(p))
+
+	v, err := unescape(escape(p))
+	assert.NoError(t, err)
+	assert.Equal(t, p, v)
+
+	_, err = unescape("/a%2")
+	assert.Error(t, err)
+	_, err = unescape("/a%zz")
+	assert.Error(t, err)
+}
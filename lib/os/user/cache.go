@@ -0,0 +1,20 @@
+package user
+
+import (
+	"github.com/akutz/gnixutils/lib/os/internal/lru"
+)
+
+// cacheSize is the number of users LookupUserID remembers.
+const cacheSize = 1024
+
+// userIDCache holds the results of LookupUserID.
+var userIDCache = lru.New(cacheSize)
+
+// copy returns a copy of u so that callers cannot change the cached user.
+func (u *User) copy() *User {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	return &c
+}
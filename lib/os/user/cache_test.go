@@ -0,0 +1,25 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/akutz/gnixutils/lib/os/internal/lru"
+)
+
+func TestLookupUserIDCached(t *testing.T) {
+	old := userIDCache
+	userIDCache = lru.New(cacheSize)
+	defer func() { userIDCache = old }()
+
+	usr, err := LookupUserID("0")
+	assert.NoError(t, err)
+	assert.NotNil(t, userIDCache.Get("0"))
+
+	// callers cannot change the cached user
+	usr.Username = "changed"
+	again, err := LookupUserID("0")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "changed", again.Username)
+}
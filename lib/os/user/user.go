@@ -0,0 +1,92 @@
+/*
+Package user looks up the entries of the user database. It is the
+companion of package group, and unlike the stdlib os/user it includes each
+user's shell and behaves the same with and without cgo.
+*/
+package user
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var (
+	// ErrUnsupported is the error for when a function is unsupported on the
+	// current OS.
+	ErrUnsupported = fmt.Errorf(
+		"Unsupported on %s_%s", runtime.GOOS, runtime.GOARCH)
+)
+
+// UnknownUserIDError is returned by LookupUserID when a user cannot be
+// found.
+type UnknownUserIDError string
+
+func (e UnknownUserIDError) Error() string {
+	return "user: unknown userid " + string(e)
+}
+
+// UnknownUserError is returned by LookupUser when a user cannot be found.
+type UnknownUserError string
+
+func (e UnknownUserError) Error() string {
+	return "user: unknown user " + string(e)
+}
+
+// User represents a user database entry.
+//
+// On posix systems ID and GroupID contain decimal numbers.
+type User struct {
+	// ID is the user's ID.
+	ID string
+
+	// GroupID is the ID of the user's login group.
+	GroupID string
+
+	// Username is the user's login name.
+	Username string
+
+	// Name is the user's GECOS field, which is usually the user's full
+	// name.
+	Name string
+
+	// HomeDir is the user's home directory.
+	HomeDir string
+
+	// Shell is the user's login shell.
+	Shell string
+}
+
+// LookupUserID looks up a user by a user's ID. If the user cannot be found,
+// the returned error is of type UnknownUserIDError. The results of recent
+// lookups are cached.
+func LookupUserID(uid string) (*User, error) {
+	if e := userIDCache.Get(uid); e != nil {
+		return e.Value.(*User).copy(), e.Err
+	}
+
+	usr, err := lookupUserID(uid)
+	if err == ErrUnsupported {
+		usr, err = &User{
+			ID:       uid,
+			Username: "",
+		}, nil
+	}
+
+	// other errors may not happen again
+	if _, ok := err.(UnknownUserIDError); err == nil || ok {
+		userIDCache.Put(uid, usr.copy(), err)
+	}
+	return usr, err
+}
+
+// LookupUser looks up a user by login name. If the user cannot be found,
+// the returned error is of type UnknownUserError. ErrUnsupported is
+// returned if users cannot be looked up on the current OS.
+func LookupUser(username string) (*User, error) {
+	return lookupUser(username)
+}
+
+// Current returns the user the process is running as.
+func Current() (*User, error) {
+	return current()
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/akutz/gnixutils/lib/os/internal/colondb"
+)
+
+// passwdFile is the user database read by the pure Go implementation.
+var passwdFile = "/etc/passwd"
+
+// passwdFields is the number of fields of an entry of a passwd file,
+// name:password:uid:gid:gecos:home:shell.
+const passwdFields = 7
+
+// readPasswdFile calls fn with each valid entry of the passwd file r until
+// fn returns true.
+func readPasswdFile(r io.Reader, fn func(*User) bool) error {
+	return colondb.Read(r, passwdFields, func(fields []string) bool {
+		u, ok := parsePasswd(fields)
+		return ok && fn(u)
+	})
+}
+
+// parsePasswd parses the fields of an entry of a passwd file.
+func parsePasswd(fields []string) (*User, bool) {
+	uid, err := strconv.ParseUint(fields[2], 10, 32)
+	if err != nil {
+		return nil, false
+	}
+	gid, err := strconv.ParseUint(fields[3], 10, 32)
+	if err != nil {
+		return nil, false
+	}
+
+	return &User{
+		ID:       strconv.FormatUint(uid, 10),
+		GroupID:  strconv.FormatUint(gid, 10),
+		Username: fields[0],
+		Name:     fields[4],
+		HomeDir:  fields[5],
+		Shell:    fields[6],
+	}, true
+}
+
+// findUser returns the first entry in the passwd file p for which match
+// returns true, or notFound if there is none.
+func findUser(
+	p string, match func(*User) bool, notFound error) (*User, error) {
+
+	var found *User
+	err := colondb.ReadFile(p, passwdFields, func(fields []string) bool {
+		if u, ok := parsePasswd(fields); ok && match(u) {
+			found = u
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, notFound
+	}
+	return found, nil
+}
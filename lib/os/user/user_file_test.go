@@ -0,0 +1,99 @@
+package user
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPasswdFile = `# a comment
+root:x:0:0:root:/root:/bin/bash
+alice:x:1000:100:Alice Liddell,,,:/home/alice:/bin/sh
+
+bob:*:1001:100::/home/bob:
++nis-user
+-excluded:x:30:30::/:/bin/false
++
+missing-fields:x:40:40::/
+bad-uid:x:abc:40::/:/bin/sh
+bad-gid:x:41:abc::/:/bin/sh
+:x:50:50::/:/bin/sh
+alice:x:1002:100::/home/duplicate:/bin/sh
+`
+
+func TestReadPasswdFile(t *testing.T) {
+	var users []*User
+	err := readPasswdFile(strings.NewReader(testPasswdFile),
+		func(u *User) bool {
+			users = append(users, u)
+			return false
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []*User{
+		{ID: "0", GroupID: "0", Username: "root", Name: "root",
+			HomeDir: "/root", Shell: "/bin/bash"},
+		{ID: "1000", GroupID: "100", Username: "alice",
+			Name: "Alice Liddell,,,", HomeDir: "/home/alice",
+			Shell: "/bin/sh"},
+		{ID: "1001", GroupID: "100", Username: "bob", HomeDir: "/home/bob"},
+		{ID: "1002", GroupID: "100", Username: "alice",
+			HomeDir: "/home/duplicate", Shell: "/bin/sh"},
+	}, users)
+}
+
+func TestReadPasswdFileStop(t *testing.T) {
+	n := 0
+	err := readPasswdFile(strings.NewReader(testPasswdFile),
+		func(u *User) bool {
+			n++
+			return u.Username == "alice"
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestReadPasswdFileLongLine(t *testing.T) {
+	long := "long:x:1:1:" + strings.Repeat("x", 100*1024) + ":/:/bin/sh\n" +
+		testPasswdFile
+	n := 0
+	err := readPasswdFile(strings.NewReader(long), func(u *User) bool {
+		n++
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+}
+
+func TestFindUser(t *testing.T) {
+	p := writePasswdFile(t)
+	defer os.RemoveAll(path.Dir(p))
+
+	u, err := findUser(p, func(u *User) bool { return u.Username == "alice" },
+		UnknownUserError("alice"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1000", u.ID)
+
+	_, err = findUser(p, func(u *User) bool { return u.ID == "30" },
+		UnknownUserIDError("30"))
+	assert.Equal(t, UnknownUserIDError("30"), err)
+
+	_, err = findUser(path.Join(path.Dir(p), "none"),
+		func(u *User) bool { return true }, nil)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func writePasswdFile(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := path.Join(dir, "passwd")
+	if err := ioutil.WriteFile(p, []byte(testPasswdFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
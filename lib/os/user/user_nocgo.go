@@ -0,0 +1,28 @@
+// +build darwin dragonfly freebsd !android,linux netbsd openbsd solaris
+// +build !cgo
+
+package user
+
+import (
+	"os"
+	"strconv"
+)
+
+func lookupUserID(uid string) (*User, error) {
+	if _, err := strconv.Atoi(uid); err != nil {
+		return nil, err
+	}
+	return findUser(passwdFile, func(u *User) bool {
+		return u.ID == uid
+	}, UnknownUserIDError(uid))
+}
+
+func lookupUser(username string) (*User, error) {
+	return findUser(passwdFile, func(u *User) bool {
+		return u.Username == username
+	}, UnknownUserError(username))
+}
+
+func current() (*User, error) {
+	return lookupUserID(strconv.Itoa(os.Getuid()))
+}
@@ -0,0 +1,58 @@
+// +build darwin dragonfly freebsd !android,linux netbsd openbsd solaris
+// +build !cgo
+
+package user
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoCgoLookupUserID(t *testing.T) {
+	usr, err := LookupUserID("0")
+	assert.NoError(t, err)
+	if assert.NotNil(t, usr) {
+		assert.Equal(t, "0", usr.ID)
+		assert.Equal(t, "root", usr.Username)
+	}
+}
+
+func TestNoCgoLookupUser(t *testing.T) {
+	usr, err := LookupUser("root")
+	assert.NoError(t, err)
+	if assert.NotNil(t, usr) {
+		assert.Equal(t, "0", usr.ID)
+	}
+
+	_, err = LookupUser("no-such-user")
+	assert.Equal(t, UnknownUserError("no-such-user"), err)
+}
+
+func TestNoCgoPasswdFile(t *testing.T) {
+	p := writePasswdFile(t)
+	defer os.RemoveAll(path.Dir(p))
+
+	old := passwdFile
+	passwdFile = p
+	defer func() { passwdFile = old }()
+
+	usr, err := LookupUser("alice")
+	assert.NoError(t, err)
+	if assert.NotNil(t, usr) {
+		assert.Equal(t, "1000", usr.ID)
+		assert.Equal(t, "100", usr.GroupID)
+		assert.Equal(t, "Alice Liddell,,,", usr.Name)
+		assert.Equal(t, "/home/alice", usr.HomeDir)
+		assert.Equal(t, "/bin/sh", usr.Shell)
+	}
+
+	// LookupUserID may have cached the system's user 1001
+	usr, err = lookupUserID("1001")
+	assert.NoError(t, err)
+	if assert.NotNil(t, usr) {
+		assert.Equal(t, "bob", usr.Username)
+	}
+}
@@ -0,0 +1,154 @@
+// +build darwin dragonfly freebsd !android,linux netbsd openbsd solaris
+// +build cgo
+
+package user
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+/*
+#cgo solaris CFLAGS: -D_POSIX_PTHREAD_SEMANTICS
+#include <unistd.h>
+#include <sys/types.h>
+#include <pwd.h>
+#include <stdlib.h>
+
+static int mygetpwuid_r(int uid, struct passwd *pwd,
+           char *buf, size_t buflen, struct passwd **result) {
+    return getpwuid_r(uid, pwd, buf, buflen, result);
+}
+
+static int mygetpwnam_r(const char *name, struct passwd *pwd,
+           char *buf, size_t buflen, struct passwd **result) {
+    return getpwnam_r(name, pwd, buf, buflen, result);
+}
+*/
+import "C"
+
+// maxBufferSize is the largest buffer lookupUnixUser allocates for a user
+// entry.
+const maxBufferSize = 1 << 20
+
+func lookupUserID(uid string) (*User, error) {
+	i, e := strconv.Atoi(uid)
+	if e != nil {
+		return nil, e
+	}
+	return lookupUnixUser(
+		func(pwd *C.struct_passwd,
+			buf *C.char,
+			size C.size_t,
+			result **C.struct_passwd) C.int {
+
+			// mygetpwuid_r is a wrapper around getpwuid_r to avoid
+			// using uid_t, as package group does for gid_t.
+			return C.mygetpwuid_r(C.int(i), pwd, buf, size, result)
+		},
+		"userid "+uid,
+		UnknownUserIDError(uid))
+}
+
+func lookupUser(username string) (*User, error) {
+	cname := C.CString(username)
+	defer C.free(unsafe.Pointer(cname))
+
+	return lookupUnixUser(
+		func(pwd *C.struct_passwd,
+			buf *C.char,
+			size C.size_t,
+			result **C.struct_passwd) C.int {
+
+			return C.mygetpwnam_r(cname, pwd, buf, size, result)
+		},
+		"user "+username,
+		UnknownUserError(username))
+}
+
+func current() (*User, error) {
+	return lookupUserID(strconv.Itoa(os.Getuid()))
+}
+
+// lookupUnixUser looks up a user with the reentrant user database function
+// called by get, which fills in pwd using the buffer buf. The error
+// notFound is returned if there is no such user, and desc describes the
+// user in other errors.
+func lookupUnixUser(
+	get func(
+		pwd *C.struct_passwd,
+		buf *C.char,
+		size C.size_t,
+		result **C.struct_passwd) C.int,
+	desc string,
+	notFound error) (*User, error) {
+
+	var pwd C.struct_passwd
+	var result *C.struct_passwd
+
+	bufSize, err := bufferSize()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		buf := C.malloc(C.size_t(bufSize))
+		rv := get(&pwd, (*C.char)(buf), C.size_t(bufSize), &result)
+
+		// the entry does not fit in the buffer
+		if syscall.Errno(rv) == syscall.ERANGE && bufSize < maxBufferSize {
+			C.free(buf)
+			bufSize *= 2
+			continue
+		}
+
+		var u *User
+		switch {
+		case rv != 0:
+			err = fmt.Errorf(
+				"user: lookup %s: %s", desc, syscall.Errno(rv))
+		case result == nil:
+			err = notFound
+		default:
+			u = buildUser(&pwd)
+		}
+		C.free(buf)
+		return u, err
+	}
+}
+
+// bufferSize returns the initial size of the buffer for the reentrant user
+// database functions.
+func bufferSize() (C.long, error) {
+	// FreeBSD doesn't have _SC_GETPW_R_SIZE_MAX and just returns -1.
+	// So just use the same size that Linux returns
+	if runtime.GOOS == "freebsd" {
+		return 1024, nil
+	}
+
+	bufSize := C.sysconf(C._SC_GETPW_R_SIZE_MAX)
+	if bufSize == -1 {
+		// there is no limit, so start small and grow the buffer
+		bufSize = 1024
+	}
+	if bufSize <= 0 || bufSize > maxBufferSize {
+		return bufSize, fmt.Errorf(
+			"user: unreasonable _SC_GETPW_R_SIZE_MAX of %d", bufSize)
+	}
+	return bufSize, nil
+}
+
+func buildUser(pwd *C.struct_passwd) *User {
+	return &User{
+		ID:       strconv.Itoa(int(pwd.pw_uid)),
+		GroupID:  strconv.Itoa(int(pwd.pw_gid)),
+		Username: C.GoString(pwd.pw_name),
+		Name:     C.GoString(pwd.pw_gecos),
+		HomeDir:  C.GoString(pwd.pw_dir),
+		Shell:    C.GoString(pwd.pw_shell),
+	}
+}
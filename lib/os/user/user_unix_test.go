@@ -0,0 +1,45 @@
+// +build darwin dragonfly freebsd !android,linux netbsd openbsd solaris
+// +build cgo
+
+package user
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixLookupUserID(t *testing.T) {
+	usr, err := LookupUserID("0")
+	assert.NoError(t, err)
+	if assert.NotNil(t, usr) {
+		assert.Equal(t, "0", usr.ID)
+		assert.Equal(t, "0", usr.GroupID)
+		assert.Equal(t, "root", usr.Username)
+		assert.NotEmpty(t, usr.HomeDir)
+	}
+
+	_, err = LookupUserID("4294967294")
+	assert.Equal(t, UnknownUserIDError("4294967294"), err)
+}
+
+func TestUnixLookupUser(t *testing.T) {
+	usr, err := LookupUser("root")
+	assert.NoError(t, err)
+	if assert.NotNil(t, usr) {
+		assert.Equal(t, "0", usr.ID)
+	}
+
+	_, err = LookupUser("no-such-user")
+	assert.Equal(t, UnknownUserError("no-such-user"), err)
+}
+
+func TestUnixCurrent(t *testing.T) {
+	usr, err := Current()
+	assert.NoError(t, err)
+	if assert.NotNil(t, usr) {
+		assert.Equal(t, strconv.Itoa(os.Getuid()), usr.ID)
+	}
+}
@@ -0,0 +1,31 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris android
+
+package user
+
+import (
+	osuser "os/user"
+)
+
+func lookupUserID(uid string) (*User, error) {
+	return nil, ErrUnsupported
+}
+
+func lookupUser(username string) (*User, error) {
+	return nil, ErrUnsupported
+}
+
+// current falls back to the stdlib, which knows the current user even
+// where the user database cannot be searched.
+func current() (*User, error) {
+	u, err := osuser.Current()
+	if err != nil {
+		return nil, err
+	}
+	return &User{
+		ID:       u.Uid,
+		GroupID:  u.Gid,
+		Username: u.Username,
+		Name:     u.Name,
+		HomeDir:  u.HomeDir,
+	}, nil
+}
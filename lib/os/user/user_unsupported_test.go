@@ -0,0 +1,22 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris android
+
+package user
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsupportedLookupUserID(t *testing.T) {
+	usr, err := LookupUserID("0")
+	assert.NoError(t, err)
+	assert.NotNil(t, usr)
+	assert.Equal(t, "0", usr.ID)
+	assert.Equal(t, "", usr.Username)
+}
+
+func TestUnsupportedLookupUser(t *testing.T) {
+	_, err := LookupUser("root")
+	assert.Equal(t, ErrUnsupported, err)
+}
@@ -0,0 +1,169 @@
+/*
+Package walk walks file trees the way the -R option of commands such as
+chmod, chown and chgrp does, following symbolic links as selected by their
+-H, -L and -P options.
+*/
+package walk
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/akutz/gnixutils/lib/os/attr"
+)
+
+// Follow selects the symbolic links that a walk follows.
+type Follow int
+
+const (
+	// Physical follows no symbolic links (-P).
+	Physical Follow = iota
+
+	// Args follows the symbolic links that are the roots of walks (-H).
+	Args
+
+	// Logical follows all symbolic links (-L).
+	Logical
+)
+
+// ErrLoop is the error passed to a Func for a directory that is one of its
+// own ancestors, which happens when symbolic links are followed.
+var ErrLoop = errors.New("file system loop detected")
+
+// Func is called for each file a walk visits. If the file is a symbolic
+// link that the walk follows then fi describes the file the link refers
+// to. If err is not nil then fi may be nil, and Func is called a second
+// time with the error for a directory that cannot be read. If Func returns
+// an error then the walk stops and returns it.
+type Func func(p string, fi os.FileInfo, err error) error
+
+// Walk walks the tree rooted at root, calling fn for each file and
+// directory in lexical order. Directories are visited before their
+// contents.
+func Walk(root string, follow Follow, fn Func) error {
+	var fi os.FileInfo
+	var err error
+	if follow == Physical {
+		fi, err = os.Lstat(root)
+	} else {
+		fi, err = os.Stat(root)
+	}
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	return walk(root, fi, follow, nil, fn)
+}
+
+func walk(
+	p string,
+	fi os.FileInfo,
+	follow Follow,
+	ancestors []attr.FileID,
+	fn Func) error {
+
+	if fi.IsDir() {
+		if id, _, ok := attr.ID(fi); ok {
+			for _, a := range ancestors {
+				if a == id {
+					return fn(p, fi, &os.PathError{
+						Op: "walk", Path: p, Err: ErrLoop})
+				}
+			}
+			ancestors = append(ancestors, id)
+		}
+	}
+
+	if err := fn(p, fi, nil); err != nil || !fi.IsDir() {
+		return err
+	}
+
+	names, err := readDirNames(p)
+	if err != nil {
+		return fn(p, fi, err)
+	}
+
+	for _, n := range names {
+		np := path.Join(p, n)
+
+		nfi, err := os.Lstat(np)
+		if err == nil && follow == Logical &&
+			nfi.Mode()&os.ModeSymlink != 0 {
+			nfi, err = os.Stat(np)
+		}
+		if err != nil {
+			if err := fn(np, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := walk(np, nfi, follow, ancestors, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readDirNames(p string) ([]string, error) {
+	d, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// followFlag implements flag.Value for the -H, -L and -P flags.
+type followFlag struct {
+	f *Follow
+	v Follow
+}
+
+func (f followFlag) String() string {
+	return "false"
+}
+
+func (f followFlag) Set(s string) error {
+	if s != "true" {
+		return fmt.Errorf("invalid value %q", s)
+	}
+	*f.f = f.v
+	return nil
+}
+
+func (f followFlag) IsBoolFlag() bool {
+	return true
+}
+
+// Flags defines the -H, -L and -P flags in fs. Whichever of them appears
+// last on the command line sets f.
+func Flags(fs *flag.FlagSet, f *Follow) {
+	fs.Var(followFlag{f, Args}, "H",
+		"With -R, follow symbolic links given on the command line")
+	fs.Var(followFlag{f, Logical}, "L",
+		"With -R, follow all symbolic links")
+	fs.Var(followFlag{f, Physical}, "P",
+		"With -R, do not follow symbolic links (the default)")
+}
+
+// Resolve returns how a -R walk of a command such as chmod, chown or chgrp
+// follows symbolic links, given the Follow selected by its -H, -L and -P
+// flags and its -h flag, noDeref. The -h flag asks for symbolic links
+// themselves to be changed, so it overrides -H, which would otherwise
+// follow the links given on the command line. With -L every link is
+// followed.
+func Resolve(follow Follow, noDeref bool) Follow {
+	if noDeref && follow == Args {
+		return Physical
+	}
+	return follow
+}
@@ -0,0 +1,142 @@
+package walk
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tree creates the files
+//
+//	root/a
+//	root/b/c
+//	root/b/up -> ..
+//	link -> root/b
+//
+// and returns the directory that contains them.
+func tree(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "walk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := path.Join(dir, "root")
+	if err := os.MkdirAll(path.Join(root, "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a", "b/c"} {
+		err := ioutil.WriteFile(path.Join(root, p), nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("..", path.Join(root, "b", "up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("root/b", path.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// visit walks root and returns the paths it visits relative to dir, with a
+// trailing slash for directories, @ for symbolic links and ! for errors.
+func visit(t *testing.T, dir, root string, follow Follow) []string {
+	var visited []string
+	err := Walk(path.Join(dir, root), follow,
+		func(p string, fi os.FileInfo, err error) error {
+			p = strings.TrimPrefix(p, dir+"/")
+			switch {
+			case err != nil:
+				p += "!"
+			case fi.IsDir():
+				p += "/"
+			case fi.Mode()&os.ModeSymlink != 0:
+				p += "@"
+			}
+			visited = append(visited, p)
+			return nil
+		})
+	assert.NoError(t, err)
+	return visited
+}
+
+func TestWalkPhysical(t *testing.T) {
+	dir := tree(t)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t,
+		[]string{"root/", "root/a", "root/b/", "root/b/c", "root/b/up@"},
+		visit(t, dir, "root", Physical))
+	assert.Equal(t, []string{"link@"}, visit(t, dir, "link", Physical))
+}
+
+func TestWalkArgs(t *testing.T) {
+	dir := tree(t)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t,
+		[]string{"link/", "link/c", "link/up@"},
+		visit(t, dir, "link", Args))
+}
+
+func TestWalkLogical(t *testing.T) {
+	dir := tree(t)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t,
+		[]string{"root/", "root/a", "root/b/", "root/b/c", "root/b/up!"},
+		visit(t, dir, "root", Logical))
+}
+
+func TestWalkMissing(t *testing.T) {
+	dir := tree(t)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t, []string{"none!"}, visit(t, dir, "none", Physical))
+}
+
+func TestWalkStop(t *testing.T) {
+	dir := tree(t)
+	defer os.RemoveAll(dir)
+
+	stop := os.ErrExist
+	n := 0
+	err := Walk(path.Join(dir, "root"), Physical,
+		func(p string, fi os.FileInfo, err error) error {
+			n++
+			if path.Base(p) == "a" {
+				return stop
+			}
+			return nil
+		})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestFlags(t *testing.T) {
+	var f Follow
+	fs := flag.NewFlagSet("walk", flag.ContinueOnError)
+	Flags(fs, &f)
+
+	assert.NoError(t, fs.Parse([]string{"-H"}))
+	assert.Equal(t, Args, f)
+
+	assert.NoError(t, fs.Parse([]string{"-L", "-P"}))
+	assert.Equal(t, Physical, f)
+
+	assert.NoError(t, fs.Parse([]string{"-P", "-L"}))
+	assert.Equal(t, Logical, f)
+}
+
+func TestResolve(t *testing.T) {
+	assert.Equal(t, Physical, Resolve(Physical, false))
+	assert.Equal(t, Args, Resolve(Args, false))
+	assert.Equal(t, Physical, Resolve(Args, true))
+	assert.Equal(t, Logical, Resolve(Logical, true))
+	assert.Equal(t, Physical, Resolve(Physical, true))
+}